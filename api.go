@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Préfixe des routes de l'API JSON
+const apiPrefix = "/api/v1/"
+
+// Représentation JSON d'un artiste
+type ArtistJSON struct {
+	ID              int           `json:"id"`
+	Name            string        `json:"name"`
+	Image           string        `json:"image"`
+	Members         []string      `json:"members"`
+	CreationDate    int           `json:"creationDate"`
+	FirstAlbum      string        `json:"firstAlbum,omitempty"`
+	ConcertCount    int           `json:"concertCount"`
+	MatchedConcerts []ConcertJSON `json:"matchedConcerts,omitempty"`
+	NearestConcert  *ConcertJSON  `json:"nearestConcert,omitempty"` // recherche "near" seulement
+	DistanceKm      *float64      `json:"distanceKm,omitempty"`
+	Tour            TourJSON      `json:"tour"`
+}
+
+// Bilan de la tournée d'un artiste. Les distances sont orthodromiques, en kilomètres arrondis à 0,1.
+type TourJSON struct {
+	DistanceKm         float64  `json:"distanceKm"`
+	LegCount           int      `json:"legCount"`
+	LongestLeg         *LegJSON `json:"longestLeg"`
+	BusiestMonth       string   `json:"busiestMonth,omitempty"` // AAAA-MM
+	BusiestMonthCount  int      `json:"busiestMonthConcerts"`
+	Countries          []string `json:"countries"`
+	CountryCount       int      `json:"countryCount"`
+	Continents         []string `json:"continents"`
+	ContinentCount     int      `json:"continentCount"`
+	UnresolvedConcerts int      `json:"unresolvedConcerts"`
+}
+
+// Trajet entre deux concerts successifs
+type LegJSON struct {
+	From       ConcertJSON `json:"from"`
+	To         ConcertJSON `json:"to"`
+	DistanceKm float64     `json:"distanceKm"`
+}
+
+// Représentation JSON d'un concert
+type ConcertJSON struct {
+	ArtistID   int      `json:"artistId"`
+	ArtistName string   `json:"artistName"`
+	Date       string   `json:"date"`
+	City       string   `json:"city"`
+	Country    string   `json:"country"`
+	Location   string   `json:"location"`
+	Upcoming   bool     `json:"upcoming"`
+	Geo        *GeoJSON `json:"geo"`
+}
+
+// Représentation JSON d'un lieu de concert
+type LocationJSON struct {
+	Location     string   `json:"location"`
+	City         string   `json:"city"`
+	Country      string   `json:"country"`
+	ArtistCount  int      `json:"artistCount"`
+	ConcertCount int      `json:"concertCount"`
+	Geo          *GeoJSON `json:"geo"`
+}
+
+// Coordonnées et noms canoniques d'un lieu d'après le gazetteer, null si le lieu est inconnu
+type GeoJSON struct {
+	Name        string  `json:"name"`
+	CountryName string  `json:"countryName"`
+	CountryCode string  `json:"countryCode"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+}
+
+// Lieu de concert le plus proche d'un point, renvoyé par /api/v1/reverse
+type ReverseJSON struct {
+	Lat        float64  `json:"lat"` // point demandé
+	Lng        float64  `json:"lng"`
+	Location   string   `json:"location"`
+	City       string   `json:"city"`
+	Country    string   `json:"country"`
+	Geo        *GeoJSON `json:"geo"`
+	DistanceKm float64  `json:"distanceKm"`
+}
+
+// Enveloppe d'une liste paginée
+type ListJSON struct {
+	Data any      `json:"data"`
+	Meta MetaJSON `json:"meta"`
+}
+
+// Informations de pagination d'une liste
+type MetaJSON struct {
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	Pages      int    `json:"pages"`
+	PerPage    int    `json:"perPage"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
+
+// Corps des réponses d'erreur de l'API
+type ErrorJSON struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+}
+
+func artistJSON(a *Artist, matched []Concert) ArtistJSON {
+	res := ArtistJSON{
+		ID:           a.ID,
+		Name:         a.Name,
+		Image:        a.Image,
+		Members:      a.Members,
+		CreationDate: a.CreationDate,
+		ConcertCount: len(a.Concerts),
+	}
+	if !a.FirstAlbum.IsZero() {
+		res.FirstAlbum = a.FirstAlbum.Format("2006-01-02")
+	}
+	for _, c := range matched {
+		res.MatchedConcerts = append(res.MatchedConcerts, concertJSON(a, c))
+	}
+	res.Tour = tourJSON(a)
+	return res
+}
+
+func tourJSON(a *Artist) TourJSON {
+	t := a.Tour
+	res := TourJSON{
+		DistanceKm:         math.Round(t.DistanceKm*10) / 10,
+		LegCount:           t.Legs,
+		BusiestMonthCount:  t.BusiestMonthCount,
+		Countries:          t.Countries,
+		CountryCount:       len(t.Countries),
+		Continents:         t.Continents,
+		ContinentCount:     len(t.Continents),
+		UnresolvedConcerts: t.UnresolvedConcerts,
+	}
+	if !t.BusiestMonth.IsZero() {
+		res.BusiestMonth = t.BusiestMonth.Format("2006-01")
+	}
+	if l := t.LongestLeg; l != nil {
+		res.LongestLeg = &LegJSON{From: concertJSON(a, l.From), To: concertJSON(a, l.To), DistanceKm: math.Round(l.Km*10) / 10}
+	}
+	return res
+}
+
+func concertJSON(a *Artist, c Concert) ConcertJSON {
+	return ConcertJSON{
+		ArtistID:   a.ID,
+		ArtistName: a.Name,
+		Date:       c.Date.Format("2006-01-02"),
+		City:       c.City,
+		Country:    c.Country,
+		Location:   c.Location,
+		Upcoming:   c.Upcoming,
+		Geo:        geoJSON(c.Geo),
+	}
+}
+
+func geoJSON(g *GeoPlace) *GeoJSON {
+	if g == nil {
+		return nil
+	}
+	return &GeoJSON{Name: g.Name, CountryName: g.Country.Name, CountryCode: g.Country.ISO, Lat: g.Lat, Lng: g.Lng}
+}
+
+// Écrit une réponse JSON
+func ecrireJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Erreur lors de l'encodage JSON : %v\n", err)
+	}
+}
+
+// Écrit une erreur JSON. Les erreurs de paramètre indiquent le paramètre fautif.
+func erreurJSON(w http.ResponseWriter, status int, err error) {
+	detail := ErrorDetail{Status: status, Message: err.Error()}
+	var paramErr *ParamError
+	if errors.As(err, &paramErr) {
+		detail.Param = paramErr.Param
+	}
+	ecrireJSON(w, status, ErrorJSON{Error: detail})
+}
+
+// Découpe une liste selon la pagination demandée (page/per_page ou cursor) et l'écrit dans l'enveloppe JSON.
+// cle renvoie l'identifiant stable d'un élément, utilisé par les curseurs.
+func ecrireListe[T any](w http.ResponseWriter, r *http.Request, items []T, cle func(T) string) {
+	q := r.URL.Query()
+	p, err := paginer(q, len(items), func(i int) string { return cle(items[i]) })
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	data := items[p.Start:p.End]
+	if data == nil {
+		data = []T{}
+	}
+	meta := MetaJSON{
+		Total:      p.Total,
+		Page:       p.Page,
+		Pages:      p.Pages,
+		PerPage:    p.PerPage,
+		PrevCursor: p.PrevCursor,
+		NextCursor: p.NextCursor,
+	}
+	if p.PrevCursor != "" {
+		meta.Prev = r.URL.Path + urlPage(q, "cursor", p.PrevCursor)
+	}
+	if p.NextCursor != "" {
+		meta.Next = r.URL.Path + urlPage(q, "cursor", p.NextCursor)
+	}
+	ecrireJSON(w, http.StatusOK, ListJSON{Data: data, Meta: meta})
+}
+
+func cleArtiste(a ArtistJSON) string {
+	return strconv.Itoa(a.ID)
+}
+
+func cleConcert(c ConcertJSON) string {
+	return strconv.Itoa(c.ArtistID) + "/" + c.Date + "/" + c.Location
+}
+
+func cleLieu(l LocationJSON) string {
+	return l.Location
+}
+
+// Point d'entrée de l'API JSON : /api/v1/...
+func apiHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		erreurJSON(w, http.StatusMethodNotAllowed, errors.New("Méthode non autorisée"))
+		return
+	}
+
+	d := currentDataset.Load()
+	if d == nil {
+		erreurJSON(w, http.StatusServiceUnavailable, errors.New("Erreur de récupération des infos API"))
+		return
+	}
+	ecrireEntetesFraicheur(w, d)
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "artists":
+		apiArtists(w, r, d)
+	case len(parts) == 2 && parts[0] == "artists":
+		apiArtist(w, r, d, parts[1])
+	case len(parts) == 3 && parts[0] == "artists" && parts[2] == "concerts":
+		apiArtistConcerts(w, r, d, parts[1])
+	case len(parts) == 3 && parts[0] == "artists" && parts[2] == "tour.geojson":
+		apiTourGeoJSON(w, r, d, parts[1])
+	case len(parts) == 1 && parts[0] == "concerts":
+		apiConcerts(w, r, d)
+	case len(parts) == 1 && parts[0] == "concerts.geojson":
+		apiConcertsGeoJSON(w, r, d)
+	case len(parts) == 1 && parts[0] == "locations":
+		apiLocations(w, r, d)
+	case len(parts) == 1 && parts[0] == "map":
+		apiMap(w, r, d)
+	case len(parts) == 1 && parts[0] == "reverse":
+		apiReverse(w, r, d)
+	default:
+		erreurJSON(w, http.StatusNotFound, errors.New("Route inconnue"))
+	}
+}
+
+// Retrouve l'artiste désigné dans l'URL, ou répond 404
+func artisteDepuisURL(w http.ResponseWriter, d *Dataset, idstr string) *Artist {
+	id, err := strconv.Atoi(idstr)
+	if err != nil {
+		erreurJSON(w, http.StatusNotFound, errors.New("Artiste introuvable"))
+		return nil
+	}
+	artist := d.ArtistModel(id)
+	if artist == nil {
+		erreurJSON(w, http.StatusNotFound, errors.New("Artiste introuvable"))
+		return nil
+	}
+	return artist
+}
+
+// Résultats de la recherche, ou répond 400 si les paramètres sont invalides
+func resultatsDepuisRequete(w http.ResponseWriter, r *http.Request, d *Dataset) ([]Result, bool) {
+	Results, err := rechercher(d, r.URL.Query())
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return Results, true
+}
+
+func apiArtists(w http.ResponseWriter, r *http.Request, d *Dataset) {
+	Results, ok := resultatsDepuisRequete(w, r, d)
+	if !ok {
+		return
+	}
+	artists := make([]ArtistJSON, len(Results))
+	for i, res := range Results {
+		artists[i] = artistJSON(res.Artist, res.Matched)
+		if res.Nearest != nil {
+			proche := concertJSON(res.Artist, *res.Nearest)
+			distance := math.Round(res.Distance*10) / 10
+			artists[i].NearestConcert, artists[i].DistanceKm = &proche, &distance
+		}
+	}
+	ecrireListe(w, r, artists, cleArtiste)
+}
+
+func apiArtist(w http.ResponseWriter, r *http.Request, d *Dataset, idstr string) {
+	artist := artisteDepuisURL(w, d, idstr)
+	if artist == nil {
+		return
+	}
+	ecrireJSON(w, http.StatusOK, artistJSON(artist, nil))
+}
+
+func apiArtistConcerts(w http.ResponseWriter, r *http.Request, d *Dataset, idstr string) {
+	artist := artisteDepuisURL(w, d, idstr)
+	if artist == nil {
+		return
+	}
+	query, err := filtreDepuisRequete(r.URL.Query())
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	concerts := []ConcertJSON{}
+	for _, c := range artist.Concerts {
+		if len(query.Concerts) == 0 || query.Concerts.MatchConcert(c) {
+			concerts = append(concerts, concertJSON(artist, c))
+		}
+	}
+	ecrireListe(w, r, concerts, cleConcert)
+}
+
+func apiConcerts(w http.ResponseWriter, r *http.Request, d *Dataset) {
+	Results, ok := resultatsDepuisRequete(w, r, d)
+	if !ok {
+		return
+	}
+	var concerts []ConcertJSON
+	for _, res := range Results {
+		for _, c := range res.RelevantConcerts() {
+			concerts = append(concerts, concertJSON(res.Artist, c))
+		}
+	}
+	sort.SliceStable(concerts, func(i, j int) bool {
+		if concerts[i].Date != concerts[j].Date {
+			return concerts[i].Date < concerts[j].Date
+		}
+		return concerts[i].ArtistID < concerts[j].ArtistID
+	})
+	ecrireListe(w, r, concerts, cleConcert)
+}
+
+func apiLocations(w http.ResponseWriter, r *http.Request, d *Dataset) {
+	Results, ok := resultatsDepuisRequete(w, r, d)
+	if !ok {
+		return
+	}
+	index := make(map[string]int)
+	var locations []LocationJSON
+	for _, res := range Results {
+		vus := make(map[string]bool)
+		for _, c := range res.RelevantConcerts() {
+			i, ok := index[c.Location]
+			if !ok {
+				i = len(locations)
+				index[c.Location] = i
+				locations = append(locations, LocationJSON{Location: c.Location, City: c.City, Country: c.Country, Geo: geoJSON(c.Geo)})
+			}
+			locations[i].ConcertCount++
+			if !vus[c.Location] {
+				vus[c.Location] = true
+				locations[i].ArtistCount++
+			}
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].Location < locations[j].Location
+	})
+	ecrireListe(w, r, locations, cleLieu)
+}
+
+// /api/v1/reverse?lat=...&lng=... : lieu de concert connu le plus proche d'un point
+func apiReverse(w http.ResponseWriter, r *http.Request, d *Dataset) {
+	q := r.URL.Query()
+	lat, err := paramCoordonnee(q, "lat", 90)
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	lng, err := paramCoordonnee(q, "lng", 180)
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	point := GeoPoint{Lat: lat, Lng: lng}
+	place, km, ok := d.Spatial.Nearest(point)
+	if !ok {
+		erreurJSON(w, http.StatusNotFound, errors.New("Aucun lieu de concert géocodé"))
+		return
+	}
+	ecrireJSON(w, http.StatusOK, ReverseJSON{
+		Lat:        lat,
+		Lng:        lng,
+		Location:   place.Key,
+		City:       place.City,
+		Country:    place.Country,
+		Geo:        geoJSON(place.Geo),
+		DistanceKm: math.Round(km*10) / 10,
+	})
+}
+
+// Lit une coordonnée obligatoire en degrés décimaux, comprise entre -limite et limite
+func paramCoordonnee(q url.Values, name string, limite float64) (float64, error) {
+	s := strings.TrimSpace(q.Get(name))
+	if s == "" {
+		return 0, &ParamError{Param: name, Value: s, Message: "paramètre obligatoire"}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, &ParamError{Param: name, Value: s, Message: "nombre décimal attendu"}
+	}
+	if v < -limite || v > limite {
+		return 0, &ParamError{Param: name, Value: s, Message: fmt.Sprintf("valeur entre %g et %g attendue", -limite, limite)}
+	}
+	return v, nil
+}
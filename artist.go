@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const artistTemplatePath = "artist.html"
+
+// Structure passée au template artist.html
+type ArtistPage struct {
+	Artist  *Artist
+	Dataset *Dataset
+}
+
+// Page d'un artiste : /artist/{id}, et son calendrier : /artist/{id}/concerts.ics
+func artistHandler(w http.ResponseWriter, r *http.Request) {
+	chemin := strings.TrimPrefix(r.URL.Path, "/artist/")
+	idstr, calendrier := strings.CutSuffix(chemin, "/concerts.ics")
+	id, err := strconv.Atoi(idstr)
+	if err != nil || id <= 0 {
+		http.Error(w, "Artiste introuvable", http.StatusNotFound)
+		return
+	}
+
+	d := datasetPourRequete(w)
+	if d == nil {
+		return
+	}
+	artist := d.ArtistModel(id)
+	if artist == nil {
+		http.Error(w, "Artiste introuvable", http.StatusNotFound)
+		return
+	}
+	if calendrier {
+		artistCalendarHandler(w, r, d, artist)
+		return
+	}
+
+	executerTemplate(w, artistTemplatePath, http.StatusOK, ArtistPage{Artist: artist, Dataset: d})
+}
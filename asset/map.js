@@ -0,0 +1,252 @@
+// Carte des concerts : projection Web Mercator dessinée en SVG, sans fond de tuiles.
+// Les marqueurs sont regroupés par le serveur (/api/v1/map) pour l'emprise et le zoom affichés.
+(function () {
+    var TUILE = 256, ZOOM_MAX = 18, LAT_MAX = 85.05112878;
+    var SVG = "http://www.w3.org/2000/svg";
+
+    var carte, svg, calques, details, recherche;
+    var zoom = 1, centre = { x: 0, y: 0 }; // centre en pixels du monde au zoom courant
+    var requete = null, minuterie = null;
+
+    function tailleMonde(z) { return TUILE * Math.pow(2, z); }
+
+    function projeter(lat, lng, z) {
+        var t = tailleMonde(z);
+        var phi = Math.max(-LAT_MAX, Math.min(LAT_MAX, lat)) * Math.PI / 180;
+        return {
+            x: (lng + 180) / 360 * t,
+            y: (1 - Math.log(Math.tan(phi) + 1 / Math.cos(phi)) / Math.PI) / 2 * t
+        };
+    }
+
+    function inverser(x, y, z) {
+        var t = tailleMonde(z);
+        return {
+            lng: x / t * 360 - 180,
+            lat: Math.atan(Math.sinh(Math.PI * (1 - 2 * y / t))) * 180 / Math.PI
+        };
+    }
+
+    function largeur() { return carte.clientWidth; }
+    function hauteur() { return carte.clientHeight; }
+
+    // Garde le monde dans la vue : pas de répétition horizontale de la carte
+    function bornerCentre() {
+        var t = tailleMonde(zoom), w = largeur(), h = hauteur();
+        centre.x = t <= w ? t / 2 : Math.max(w / 2, Math.min(t - w / 2, centre.x));
+        centre.y = t <= h ? t / 2 : Math.max(h / 2, Math.min(t - h / 2, centre.y));
+    }
+
+    function ecran(lat, lng) {
+        var p = projeter(lat, lng, zoom);
+        return { x: p.x - centre.x + largeur() / 2, y: p.y - centre.y + hauteur() / 2 };
+    }
+
+    function emprise() {
+        var w = largeur(), h = hauteur();
+        var no = inverser(centre.x - w / 2, centre.y - h / 2, zoom);
+        var se = inverser(centre.x + w / 2, centre.y + h / 2, zoom);
+        var borne = function (v, m) { return Math.max(-m, Math.min(m, v)).toFixed(4); };
+        return [borne(no.lng, 180), borne(se.lat, 90), borne(se.lng, 180), borne(no.lat, 90)].join(",");
+    }
+
+    function element(nom, attributs, parent) {
+        var el = document.createElementNS(SVG, nom);
+        for (var a in attributs) el.setAttribute(a, attributs[a]);
+        if (parent) parent.appendChild(el);
+        return el;
+    }
+
+    function vider(el) { while (el.firstChild) el.removeChild(el.firstChild); }
+
+    // Méridiens et parallèles, plus serrés quand on zoome
+    function dessinerGraticule() {
+        var g = calques.graticule;
+        vider(g);
+        var pas = zoom <= 2 ? 30 : zoom <= 4 ? 10 : zoom <= 6 ? 5 : 1;
+        var haut = ecran(LAT_MAX, 0).y, bas = ecran(-LAT_MAX, 0).y;
+        var gauche = ecran(0, -180).x, droite = ecran(0, 180).x;
+        element("rect", { x: gauche, y: haut, width: droite - gauche, height: bas - haut, "class": "carte-monde" }, g);
+        for (var lng = -180; lng <= 180; lng += pas) {
+            var x = ecran(0, lng).x;
+            if (x < 0 || x > largeur()) continue;
+            element("line", { x1: x, y1: haut, x2: x, y2: bas, "class": lng === 0 ? "carte-repere" : "carte-ligne" }, g);
+        }
+        for (var lat = -80; lat <= 80; lat += pas) {
+            var y = ecran(lat, 0).y;
+            if (y < 0 || y > hauteur()) continue;
+            element("line", { x1: gauche, y1: y, x2: droite, y2: y, "class": lat === 0 ? "carte-repere" : "carte-ligne" }, g);
+        }
+    }
+
+    function dessinerPays(pays) {
+        var g = calques.pays;
+        vider(g);
+        pays.forEach(function (p) {
+            var e = ecran(p.lat, p.lng);
+            var t = element("text", { x: e.x, y: e.y, "class": "carte-pays" }, g);
+            t.textContent = p.name;
+        });
+    }
+
+    function dessinerGroupes(groupes) {
+        var g = calques.groupes;
+        vider(g);
+        groupes.forEach(function (c) {
+            var e = ecran(c.lat, c.lng);
+            var r = 6 + 4 * Math.log2(c.concertCount);
+            var m = element("g", { "class": "carte-groupe", transform: "translate(" + e.x + "," + e.y + ")", tabindex: 0 }, g);
+            element("title", {}, m).textContent = c.label + " : " + c.concertCount + " concert(s), " + c.artistCount + " artiste(s)";
+            element("circle", { r: r }, m);
+            element("text", { "class": "carte-nombre", dy: "0.35em" }, m).textContent = c.concertCount;
+            if (c.locationCount === 1 && zoom >= 3) {
+                element("text", { "class": "carte-ville", x: r + 3, dy: "0.35em" }, m).textContent = c.label;
+            }
+            m.addEventListener("click", function (ev) { ev.stopPropagation(); afficherDetails(c); });
+            m.addEventListener("keydown", function (ev) { if (ev.key === "Enter") afficherDetails(c); });
+        });
+    }
+
+    // Liste des artistes et des dates du groupe choisi
+    function afficherDetails(c) {
+        vider(details);
+        var titre = document.createElement("h2");
+        titre.textContent = c.label;
+        details.appendChild(titre);
+        if (c.locationCount > 1) {
+            var bouton = document.createElement("button");
+            bouton.type = "button";
+            bouton.textContent = "Zoomer sur ces " + c.locationCount + " lieux";
+            bouton.addEventListener("click", function () { cadrer(c.bbox); });
+            details.appendChild(bouton);
+        }
+        c.artists.forEach(function (a) {
+            var h = document.createElement("h3");
+            var lien = document.createElement("a");
+            lien.href = "/artist/" + a.id;
+            lien.textContent = a.name;
+            h.appendChild(lien);
+            details.appendChild(h);
+            var ul = document.createElement("ul");
+            ul.className = "concerts";
+            a.concerts.forEach(function (co) {
+                var li = document.createElement("li");
+                li.textContent = co.date.split("-").reverse().join("-") + " - " + co.city + ", " + co.country + (co.upcoming ? " (à venir)" : "");
+                ul.appendChild(li);
+            });
+            details.appendChild(ul);
+        });
+    }
+
+    // Zoom le plus fort qui montre toute l'emprise ouest,sud,est,nord
+    function cadrer(b) {
+        var z = ZOOM_MAX;
+        while (z > 0) {
+            var no = projeter(b[3], b[0], z), se = projeter(b[1], b[2], z);
+            if (se.x - no.x <= largeur() * 0.8 && se.y - no.y <= hauteur() * 0.8) break;
+            z--;
+        }
+        zoom = Math.min(z, ZOOM_MAX);
+        var no2 = projeter(b[3], b[0], zoom), se2 = projeter(b[1], b[2], zoom);
+        centre = { x: (no2.x + se2.x) / 2, y: (no2.y + se2.y) / 2 };
+        rafraichir();
+    }
+
+    function zoomer(delta, ex, ey) {
+        var nouveau = Math.max(0, Math.min(ZOOM_MAX, zoom + delta));
+        if (nouveau === zoom) return;
+        // Le point sous le curseur reste en place
+        if (ex === undefined) { ex = largeur() / 2; ey = hauteur() / 2; }
+        var mx = centre.x - largeur() / 2 + ex, my = centre.y - hauteur() / 2 + ey;
+        var f = Math.pow(2, nouveau - zoom);
+        centre = { x: mx * f - ex + largeur() / 2, y: my * f - ey + hauteur() / 2 };
+        zoom = nouveau;
+        rafraichir();
+    }
+
+    function charger() {
+        if (requete) requete.abort();
+        requete = new AbortController();
+        var url = "/api/v1/map?" + (recherche ? recherche + "&" : "") + "bbox=" + emprise() + "&zoom=" + zoom;
+        fetch(url, { signal: requete.signal })
+            .then(function (r) { return r.json(); })
+            .then(function (data) {
+                if (data.error) {
+                    details.textContent = data.error.message;
+                    return;
+                }
+                dessinerPays(data.countries);
+                dessinerGroupes(data.clusters);
+            })
+            .catch(function (err) {
+                if (err.name !== "AbortError") console.error("Erreur lors du chargement de la carte :", err);
+            });
+    }
+
+    // Redessine le fond tout de suite et recharge les marqueurs une fois le mouvement fini
+    function rafraichir() {
+        bornerCentre();
+        dessinerGraticule();
+        clearTimeout(minuterie);
+        minuterie = setTimeout(charger, 150);
+    }
+
+    // Pendant un glissement, les marqueurs déjà chargés suivent la carte sans nouvelle requête
+    function deplacer(depart, dx, dy) {
+        centre = { x: depart.cx - dx, y: depart.cy - dy };
+        bornerCentre();
+        var t = "translate(" + (depart.cx - centre.x) + "," + (depart.cy - centre.y) + ")";
+        calques.pays.setAttribute("transform", t);
+        calques.groupes.setAttribute("transform", t);
+    }
+
+    document.addEventListener("DOMContentLoaded", function () {
+        carte = document.getElementById("carte");
+        details = document.getElementById("carte-details");
+        recherche = carte.dataset.search || "";
+        svg = element("svg", { width: "100%", height: "100%" }, carte);
+        calques = {
+            graticule: element("g", {}, svg),
+            pays: element("g", {}, svg),
+            groupes: element("g", {}, svg)
+        };
+
+        zoom = Math.max(0, Math.ceil(Math.log2(largeur() / TUILE)));
+        centre = { x: tailleMonde(zoom) / 2, y: tailleMonde(zoom) / 2 };
+
+        // Glisser pour se déplacer
+        var depart = null;
+        carte.addEventListener("pointerdown", function (ev) {
+            if (ev.target.closest(".carte-groupe")) return;
+            depart = { x: ev.clientX, y: ev.clientY, cx: centre.x, cy: centre.y };
+            carte.setPointerCapture(ev.pointerId);
+        });
+        carte.addEventListener("pointermove", function (ev) {
+            if (!depart) return;
+            deplacer(depart, ev.clientX - depart.x, ev.clientY - depart.y);
+            dessinerGraticule();
+        });
+        carte.addEventListener("pointerup", function () {
+            if (!depart) return;
+            depart = null;
+            calques.pays.removeAttribute("transform");
+            calques.groupes.removeAttribute("transform");
+            rafraichir();
+        });
+
+        carte.addEventListener("wheel", function (ev) {
+            ev.preventDefault();
+            var b = carte.getBoundingClientRect();
+            zoomer(ev.deltaY < 0 ? 1 : -1, ev.clientX - b.left, ev.clientY - b.top);
+        }, { passive: false });
+        carte.addEventListener("dblclick", function (ev) {
+            var b = carte.getBoundingClientRect();
+            zoomer(1, ev.clientX - b.left, ev.clientY - b.top);
+        });
+        document.getElementById("zoom-plus").addEventListener("click", function () { zoomer(1); });
+        document.getElementById("zoom-moins").addEventListener("click", function () { zoomer(-1); });
+        window.addEventListener("resize", rafraichir);
+
+        rafraichir();
+    });
+})();
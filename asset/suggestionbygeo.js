@@ -1,23 +1,40 @@
 $(document).ready(function() {
+    // Élément de la liste pour un lieu renvoyé par /suggestgeo : choisir le lieu filtre exactement celui-ci
+    function elementLieu(lieu, libelle) {
+        var li = $("<li>").addClass("lieu-" + lieu.type).data("place", lieu.place).data("label", libelle);
+        li.append($("<span>").text(lieu.label));
+        li.append($("<small>").text(" (" + lieu.artistCount + " artiste(s), " + lieu.concertCount + " concert(s))"));
+        return li;
+    }
+
     $("#autocompletegeo").on("input", function() {
         var query = $(this).val();
+        $("#place").val(""); // la saisie libre remplace le lieu choisi
+        $("#near").val("");  // et la recherche autour de la position
+        $(this).attr("placeholder", "Search for a location ...");
+        if (query.trim() === "") {
+            $("#suggestionsloc").empty();
+            return;
+        }
         $.ajax({
-            url: "/suggestgeo?query=" + query,
+            url: "/suggestgeo",
+            data: { query: query, limit: 10 },
             method: "GET",
+            dataType: "json",
             success: function(data) {
                 $("#suggestionsloc").empty();
-                var suggestionA = data.split(",");
-                console.log("Suggestions array:", suggestionA);
-                if (suggestionA.length === 1 && suggestionA[0] === "null\n") {
-                    $("#suggestionsloc").append("<li>No artist matching!</li>");
-                } else {
-                    // Ajouter l'élément "Géolocalisation" en premier
-                    $("#suggestionsloc").append("<li id='geocalisationItem'>Géolocalisation</li>");
-                    suggestionA.forEach(function(suggestion) {
-                        suggestion = suggestion.replace(/["\[\]]/g, '');
-                        $("#suggestionsloc").append("<li>" + suggestion + "</li>");
-                    });
+                // Ajouter l'élément "Géolocalisation" en premier
+                $("#suggestionsloc").append("<li id='geocalisationItem'>Géolocalisation</li>");
+                if (data.length === 0) {
+                    $("#suggestionsloc").append("<li>No location matching!</li>");
+                    return;
                 }
+                data.forEach(function(pays) {
+                    $("#suggestionsloc").append(elementLieu(pays, pays.label));
+                    (pays.cities || []).forEach(function(ville) {
+                        $("#suggestionsloc").append(elementLieu(ville, ville.label + ", " + pays.label));
+                    });
+                });
             },
             error: function() {
                 // Gérer les erreurs
@@ -27,13 +44,13 @@ $(document).ready(function() {
     });
     
     $(document).on("click", "#suggestionsloc li", function() {
-        var suggestion = $(this).text();
-        if (suggestion === "Géolocalisation") {
+        if ($(this).is("#geocalisationItem")) {
             geo(); // Appeler la fonction geo() lorsque l'utilisateur sélectionne "Géolocalisation"
-        } else {
-            $("#autocompletegeo").val(suggestion);
-            $("#suggestionsloc").empty(); // Vide les suggestions après que l'utilisateur en a sélectionné une
+            return;
         }
+        $("#autocompletegeo").val($(this).data("label") || $(this).text());
+        $("#place").val($(this).data("place") || "");
+        $("#suggestionsloc").empty(); // Vide les suggestions après que l'utilisateur en a sélectionné une
     });
     // Gestionnaire d'événements pour fermer les suggestions en dehors de la zone de suggestion
     $(document).on("click", function(event) {
@@ -48,14 +65,15 @@ function geo() {
         navigator.geolocation.getCurrentPosition(function(position) {
             var latitudeuser = position.coords.latitude;
             var longitudeuser = position.coords.longitude;
-            console.log('Latitude : ', latitudeuser);
-            console.log('Longitude : ', longitudeuser);
-            
+
+            // La recherche porte sur les concerts autour de la position (paramètres near et radius_km)
+            $("#near").val(latitudeuser.toFixed(4) + "," + longitudeuser.toFixed(4));
+            $("#place").val("");
+            $("#autocompletegeo").val("").attr("placeholder", "Autour de ma position");
+            $("#suggestionsloc").empty();
+
             obtenirNomVillePays(latitudeuser, longitudeuser, function(city, country) {
-                $("#suggestionsloc").empty(); // Vider les suggestions
-                // Ajouter l'élément "Géolocalisation" en premier
-                $("#suggestionsloc").append("<li>"+ city +"</li>");
-                $("#suggestionsloc").append("<li>"+ country +"</li>");
+                $("#autocompletegeo").attr("placeholder", "Autour de " + city + ", " + country);
             });
         }, function(error) {
             console.error('Erreur lors de la récupération de la position : ', error.message);
@@ -64,22 +82,19 @@ function geo() {
         console.error("La géolocalisation n'est pas prise en charge par ce navigateur.");
     }
 
+    // Lieu de concert connu le plus proche, trouvé par le serveur sans service externe
     function obtenirNomVillePays(latitude, longitude, callback) {
-        var url = `/geonames?lat=${latitude}&lng=${longitude}`;
-        
-        fetch(url)
-            .then(response => response.json())
-            .then(data => {
-                if (data.geonames.length > 0) {
-                    var city = data.geonames[0].name;
-                    var country = data.geonames[0].countryName;
-                    console.log('Ville : ', city);
-                    console.log('Pays : ', country);
-                    callback(city, country);
-                } else {
-                    console.log('Aucune information trouvée.');
-                }
-            })
-            .catch(error => console.error('Erreur lors de la récupération du nom de la ville/pays : ', error));
+        $.ajax({
+            url: "/api/v1/reverse",
+            data: { lat: latitude, lng: longitude },
+            method: "GET",
+            dataType: "json",
+            success: function(data) {
+                callback(data.city, data.country);
+            },
+            error: function(xhr) {
+                console.error("Erreur lors de la récupération du nom de la ville/pays : ", xhr.responseJSON ? xhr.responseJSON.error.message : xhr.statusText);
+            }
+        });
     }
 }
@@ -1,22 +1,37 @@
 $(document).ready(function() {
+    // Libellés affichés pour chaque type de suggestion renvoyé par /suggest
+    var typesSuggestion = {
+        artist: "Artiste",
+        member: "Membre",
+        city: "Ville",
+        country: "Pays",
+        year: "1er album"
+    };
+
     $("#autocomplete").on("input", function() {
         var query = $(this).val();
+        if (query.trim() === "") {
+            $("#suggestions").empty();
+            return;
+        }
         $.ajax({
-            url: "/suggest?query=" + query,
+            url: "/suggest",
+            data: { query: query, limit: 10 },
             method: "GET",
+            dataType: "json",
             success: function(data) {
                 $("#suggestions").empty();
-                var suggestionA = data.split(",");
-                console.log("Suggestions array:", suggestionA);
-                if (suggestionA.length === 1 && suggestionA[0] === "null\n") {
+                if (data.length === 0) {
                     $("#suggestions").append("<li>No artist matching!</li>");
-                } else {
-                    suggestionA.forEach(function(suggestion) {
-                        suggestion = suggestion.replace(/["\[\]]/g, '');
-                        $("#suggestions").append("<li>" + suggestion + "</li>");
-                    });
+                    return;
                 }
-                
+                data.forEach(function(suggestion) {
+                    // Le texte est inséré avec .text() : les noms peuvent contenir des virgules ou du HTML
+                    var li = $("<li>").addClass("suggestion").data("search", suggestion.search);
+                    li.append($("<span>").text(suggestion.label));
+                    li.append($("<small>").text(" - " + (typesSuggestion[suggestion.type] || suggestion.type)));
+                    $("#suggestions").append(li);
+                });
             },
             error: function() {
                 // Gérer les erreurs
@@ -25,9 +40,8 @@ $(document).ready(function() {
         });
     });
 
-    $(document).on("click", "#suggestions li", function() {
-        var suggestion = $(this).text();
-        $("#autocomplete").val(suggestion);
+    $(document).on("click", "#suggestions li.suggestion", function() {
+        $("#autocomplete").val($(this).data("search"));
         $("#suggestions").empty(); // Vide les suggestions après que l'utilisateur en a sélectionné une
     });
     // Gestionnaire d'événements pour fermer les suggestions en dehors de la zone de suggestion
@@ -36,4 +50,4 @@ $(document).ready(function() {
             $("#suggestions").empty();
         }
     });
-});
\ No newline at end of file
+});
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Structure Dataset : instantané en mémoire des quatre flux de l'API, joints par ID d'artiste.
+// Un Dataset n'est jamais modifié après sa construction, il est remplacé en entier à chaque rafraîchissement.
+type Dataset struct {
+	Artists   []ArtistsInfo
+	Locations LocationsInfo
+	Dates     DatesInfo
+	Relations RelationsInfo
+	LoadedAt  time.Time
+
+	// Modèle typé construit à partir des flux
+	Model     []*Artist
+	Places    []Location
+	Anomalies []Anomaly
+	Index     *InvertedIndex
+
+	// Clés de lieu sans coordonnées dans le gazetteer
+	Unresolved []string
+	// Lieux géocodés, pour retrouver le plus proche d'un point
+	Spatial *KDTree
+
+	modelByID map[int]*Artist
+}
+
+// Instantané courant partagé par tous les handlers
+var currentDataset atomic.Pointer[Dataset]
+
+// Récupère les quatre flux depuis la source et construit un Dataset
+func chargerDataset(src Source) (*Dataset, error) {
+	f, err := src.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	return newDataset(f.Artists, f.Locations, f.Dates, f.Relations), nil
+}
+
+// Construit un Dataset, son modèle typé et ses index
+func newDataset(artists []ArtistsInfo, locations LocationsInfo, dates DatesInfo, relations RelationsInfo) *Dataset {
+	d := &Dataset{
+		Artists:   artists,
+		Locations: locations,
+		Dates:     dates,
+		Relations: relations,
+		LoadedAt:  time.Now(),
+	}
+
+	d.Model, d.Places, d.Anomalies = construireModele(artists, locations, dates, relations)
+	d.modelByID = make(map[int]*Artist, len(d.Model))
+	for _, a := range d.Model {
+		d.modelByID[a.ID] = a
+	}
+	d.Unresolved = geocoder(gazetteer, d.Model, d.Places)
+	for _, a := range d.Model {
+		a.Tour = calculerTournee(gazetteer, a.Concerts)
+	}
+	d.Spatial = construireKDTree(d.Places)
+	d.Index = construireIndex(d.Model, d.Places)
+	return d
+}
+
+// Charge un nouveau Dataset et remplace atomiquement l'instantané courant.
+// En cas d'erreur l'instantané courant est conservé.
+func rafraichirDataset(src Source) error {
+	d, err := chargerDataset(src)
+	if err != nil {
+		log.Printf("Erreur lors du chargement des données depuis %s : %v\n", src, err)
+		return err
+	}
+	currentDataset.Store(d)
+	log.Printf("Données chargées depuis %s : %d artistes\n", src, len(d.Artists))
+	signalerAnomalies(d.Anomalies)
+	if len(d.Unresolved) > 0 {
+		log.Printf("%d lieu(x) sans coordonnées dans le gazetteer : %s\n", len(d.Unresolved), strings.Join(d.Unresolved, ", "))
+	}
+	return nil
+}
+
+// Retourne l'artiste du modèle typé correspondant à l'ID, nil s'il n'existe pas
+func (d *Dataset) ArtistModel(id int) *Artist {
+	return d.modelByID[id]
+}
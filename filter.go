@@ -0,0 +1,730 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filtre de recherche appliqué à un artiste
+type Filter interface {
+	Match(*Artist) bool
+}
+
+// Filtre portant sur un concert précis de l'artiste (lieu, date...)
+type ConcertFilter interface {
+	Filter
+	MatchConcert(Concert) bool
+}
+
+// Garde les artistes qui vérifient tous les filtres
+type And []Filter
+
+func (f And) Match(a *Artist) bool {
+	for _, filtre := range f {
+		if !filtre.Match(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// Garde les artistes qui vérifient au moins un des filtres
+type Or []Filter
+
+func (f Or) Match(a *Artist) bool {
+	for _, filtre := range f {
+		if filtre.Match(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Garde les concerts qui vérifient au moins un des filtres, pour qu'une alternative entre deux
+// lieux ou deux critères de concert ("city:paris OR city:london") reste combinable avec les autres
+// critères du même concert
+type AnyConcert []ConcertFilter
+
+func (f AnyConcert) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f AnyConcert) MatchConcert(c Concert) bool {
+	for _, filtre := range f {
+		if filtre.MatchConcert(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Garde les artistes qui ne vérifient pas le filtre
+type Not struct {
+	Filter Filter
+}
+
+func (f Not) Match(a *Artist) bool {
+	return !f.Filter.Match(a)
+}
+
+// Garde les artistes ayant au moins un concert qui vérifie tous les filtres à la fois.
+// Un lieu et une date doivent donc correspondre au même concert.
+type SameConcert []ConcertFilter
+
+func (f SameConcert) Match(a *Artist) bool {
+	for _, c := range a.Concerts {
+		if f.MatchConcert(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f SameConcert) MatchConcert(c Concert) bool {
+	for _, filtre := range f {
+		if !filtre.MatchConcert(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Nom de l'artiste ou d'un de ses membres, avec tolérance aux fautes de frappe
+type NameFilter struct {
+	Query string
+}
+
+func (f NameFilter) Match(a *Artist) bool {
+	_, _, ok := f.Score(a)
+	return ok
+}
+
+// Meilleure correspondance entre la recherche et le nom de l'artiste ou de ses membres.
+// À score égal, le nom de l'artiste passe avant celui d'un membre.
+func (f NameFilter) Score(a *Artist) (Match, Highlight, bool) {
+	meilleur, ok := rechercheApprochee(f.Query, a.Name)
+	var h Highlight
+	if ok {
+		h = surligner("name", a.Name, meilleur)
+	}
+	for _, membre := range a.Members {
+		m, trouve := rechercheApprochee(f.Query, membre)
+		if trouve && (!ok || m.Score > meilleur.Score) {
+			meilleur, h, ok = m, surligner("member", membre, m), true
+		}
+	}
+	return meilleur, h, ok
+}
+
+// Lieu de concert : chaque mot recherché doit apparaître dans la ville ou le pays
+type LocationFilter struct {
+	Query string
+}
+
+func (f LocationFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f LocationFilter) MatchConcert(c Concert) bool {
+	locationWords := strings.Fields(normaliser(c.Location))
+	for _, word := range strings.Fields(normaliser(f.Query)) {
+		trouve := false
+		for _, locWord := range locationWords {
+			if strings.Contains(locWord, word) {
+				trouve = true
+				break
+			}
+		}
+		if !trouve {
+			return false
+		}
+	}
+	return true
+}
+
+// Lieu exact choisi dans les suggestions : clé "ville-pays" de l'API pour une ville, partie
+// pays de la clé seule pour un pays ("uk" ne trouve que les concerts au Royaume-Uni).
+// Les clés sont comparées normalisées : "sao_paulo-brazil" trouve aussi "são_paulo-brazil".
+type PlaceFilter struct {
+	Place string
+}
+
+func (f PlaceFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f PlaceFilter) MatchConcert(c Concert) bool {
+	return cleLieuNormalisee(c.Location, !strings.Contains(f.Place, "-")) == cleLieuNormalisee(f.Place, false)
+}
+
+// Forme normalisée d'une clé de lieu, réduite à la partie pays si pays est vrai
+func cleLieuNormalisee(cle string, pays bool) string {
+	if pays {
+		_, cle, _ = strings.Cut(cle, "-")
+	}
+	return normaliser(cle)
+}
+
+// Ville de concert : chaque mot recherché doit être un mot entier de la ville
+type CityFilter struct {
+	Query string
+}
+
+func (f CityFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f CityFilter) MatchConcert(c Concert) bool {
+	ville, _, _ := strings.Cut(c.Location, "-")
+	return motsEntiers(f.Query, ville)
+}
+
+// Pays de concert : chaque mot recherché doit être un mot entier du pays ("uk" ne trouve pas "ukraine")
+type CountryFilter struct {
+	Query string
+}
+
+func (f CountryFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f CountryFilter) MatchConcert(c Concert) bool {
+	_, pays, _ := strings.Cut(c.Location, "-")
+	return motsEntiers(f.Query, pays)
+}
+
+// Vrai si chaque mot normalisé de query est un mot de texte
+func motsEntiers(query, texte string) bool {
+	mots := strings.Fields(normaliser(texte))
+	recherche := strings.Fields(normaliser(query))
+	if len(recherche) == 0 {
+		return false
+	}
+	for _, word := range recherche {
+		trouve := false
+		for _, m := range mots {
+			if m == word {
+				trouve = true
+				break
+			}
+		}
+		if !trouve {
+			return false
+		}
+	}
+	return true
+}
+
+// Nom de l'artiste uniquement, avec tolérance aux fautes de frappe
+type ArtistNameFilter struct {
+	Query string
+}
+
+func (f ArtistNameFilter) Match(a *Artist) bool {
+	_, ok := rechercheApprochee(f.Query, a.Name)
+	return ok
+}
+
+// Nom d'un des membres uniquement, avec tolérance aux fautes de frappe
+type MemberFilter struct {
+	Query string
+}
+
+func (f MemberFilter) Match(a *Artist) bool {
+	for _, membre := range a.Members {
+		if _, ok := rechercheApprochee(f.Query, membre); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Concert à une date précise
+type ConcertDateFilter struct {
+	Date time.Time
+}
+
+func (f ConcertDateFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f ConcertDateFilter) MatchConcert(c Concert) bool {
+	return c.Date.Equal(f.Date)
+}
+
+// Concert entre From et To inclus (date zéro : pas de borne)
+type ConcertPeriodFilter struct {
+	From, To time.Time
+}
+
+func (f ConcertPeriodFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f ConcertPeriodFilter) MatchConcert(c Concert) bool {
+	return dateDansIntervalle(c.Date, f.From, f.To)
+}
+
+// Concert à venir (marqué d'un "*" dans l'api Dates) ou passé
+type UpcomingFilter struct {
+	Upcoming bool
+}
+
+func (f UpcomingFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f UpcomingFilter) MatchConcert(c Concert) bool {
+	return c.Upcoming == f.Upcoming
+}
+
+// Concert à moins de RadiusKm kilomètres d'un point. Les lieux absents du gazetteer ne sont jamais retenus.
+type NearFilter struct {
+	Center   GeoPoint
+	RadiusKm float64
+}
+
+func (f NearFilter) Match(a *Artist) bool {
+	return SameConcert{f}.Match(a)
+}
+
+func (f NearFilter) MatchConcert(c Concert) bool {
+	return c.Geo != nil && distanceKm(f.Center, c.Geo.GeoPoint) <= f.RadiusKm
+}
+
+// Année de création du groupe comprise entre Min et Max (0 : pas de borne)
+type CreationYearFilter struct {
+	Min, Max int
+}
+
+func (f CreationYearFilter) Match(a *Artist) bool {
+	return dansIntervalle(a.CreationDate, f.Min, f.Max)
+}
+
+// Nombre de membres compris entre Min et Max (0 : pas de borne)
+type MemberCountFilter struct {
+	Min, Max int
+}
+
+func (f MemberCountFilter) Match(a *Artist) bool {
+	return dansIntervalle(len(a.Members), f.Min, f.Max)
+}
+
+// Date de sortie du premier album comprise entre From et To inclus (date zéro : pas de borne)
+type FirstAlbumFilter struct {
+	From, To time.Time
+}
+
+func (f FirstAlbumFilter) Match(a *Artist) bool {
+	if a.FirstAlbum.IsZero() {
+		return false
+	}
+	return dateDansIntervalle(a.FirstAlbum, f.From, f.To)
+}
+
+func dansIntervalle(v, min, max int) bool {
+	return (min == 0 || v >= min) && (max == 0 || v <= max)
+}
+
+func dateDansIntervalle(date, from, to time.Time) bool {
+	return (from.IsZero() || !date.Before(from)) && (to.IsZero() || !date.After(to))
+}
+
+// Structure Query : filtres d'une recherche, avec à part les critères portant sur les concerts
+type Query struct {
+	Filter   And
+	Concerts SameConcert
+	Text     *NameFilter // recherche textuelle, nil si aucune
+	Near     *NearFilter // recherche autour d'un point, nil si aucune
+}
+
+// Structure Result : artiste trouvé et, si la recherche porte sur les concerts, ceux qui correspondent
+type Result struct {
+	*Artist
+	Matched   []Concert
+	Score     float64    // pertinence de la recherche textuelle
+	Highlight *Highlight // passage du nom ou du membre qui correspond à la recherche
+	Nearest   *Concert   // concert retenu le plus proche du point recherché
+	Distance  float64    // distance en kilomètres de ce concert
+}
+
+// Concerts à afficher pour un résultat : ceux qui correspondent à la recherche si elle porte
+// sur les concerts, tous sinon
+func (r Result) RelevantConcerts() []Concert {
+	if len(r.Matched) > 0 {
+		return r.Matched
+	}
+	return r.Concerts
+}
+
+// Applique la recherche à une liste d'artistes
+func (q *Query) Run(d *Dataset) []Result {
+	var Results []Result
+	candidats := q.candidats(d.Index)
+	for _, artist := range d.Model {
+		if candidats != nil && !candidats[artist.ID] {
+			continue
+		}
+		if !q.Filter.Match(artist) {
+			continue
+		}
+		res := Result{Artist: artist}
+		if q.Text != nil {
+			m, h, _ := q.Text.Score(artist)
+			res.Score, res.Highlight = m.Score, &h
+		}
+		if len(q.Concerts) > 0 {
+			for _, c := range artist.Concerts {
+				if q.Concerts.MatchConcert(c) {
+					res.Matched = append(res.Matched, c)
+				}
+			}
+		}
+		if q.Near != nil {
+			for i, c := range res.Matched {
+				if dist := distanceKm(q.Near.Center, c.Geo.GeoPoint); res.Nearest == nil || dist < res.Distance {
+					res.Nearest, res.Distance = &res.Matched[i], dist
+				}
+			}
+		}
+		Results = append(Results, res)
+	}
+	return Results
+}
+
+// Artistes pouvant correspondre à la recherche d'après l'index inversé, nil si l'index ne
+// permet pas de réduire la liste. Les filtres restent appliqués ensuite à chaque candidat.
+func (q *Query) candidats(ix *InvertedIndex) idSet {
+	var res idSet
+	restreindre := func(s idSet) {
+		if res == nil {
+			res = s
+		} else {
+			res = res.intersection(s)
+		}
+	}
+	if q.Text != nil {
+		restreindre(ix.CandidatsTexte(q.Text.Query))
+	}
+	for _, f := range q.Concerts {
+		switch f := f.(type) {
+		case LocationFilter:
+			if s := ix.CandidatsLieu(f.Query); s != nil {
+				restreindre(s)
+			}
+		case ConcertDateFilter:
+			restreindre(ix.CandidatsAnnees(f.Date.Year(), f.Date.Year()))
+		case PlaceFilter:
+			if s := ix.CandidatsLieu(f.Place); s != nil {
+				restreindre(s)
+			}
+		case CityFilter:
+			if s := ix.CandidatsLieu(f.Query); s != nil {
+				restreindre(s)
+			}
+		case CountryFilter:
+			if s := ix.CandidatsLieu(f.Query); s != nil {
+				restreindre(s)
+			}
+		}
+	}
+	return res
+}
+
+// Classe les résultats du plus au moins pertinent pour la recherche textuelle
+func trierParPertinence(Results []Result) []Result {
+	tries := make([]Result, len(Results))
+	copy(tries, Results)
+	sort.SliceStable(tries, func(i, j int) bool {
+		if tries[i].Score != tries[j].Score {
+			return tries[i].Score > tries[j].Score
+		}
+		return tries[i].ID < tries[j].ID
+	})
+	return tries
+}
+
+// Classe les résultats du concert le plus proche au plus éloigné
+func trierParDistance(Results []Result) []Result {
+	tries := make([]Result, len(Results))
+	copy(tries, Results)
+	sort.SliceStable(tries, func(i, j int) bool {
+		if tries[i].Distance != tries[j].Distance {
+			return tries[i].Distance < tries[j].Distance
+		}
+		return tries[i].ID < tries[j].ID
+	})
+	return tries
+}
+
+// Résultats sans filtre : tous les artistes
+func tousLesResultats(data []*Artist) []Result {
+	Results := make([]Result, len(data))
+	for i, artist := range data {
+		Results[i] = Result{Artist: artist}
+	}
+	return Results
+}
+
+// Erreur sur un paramètre de recherche fourni par l'utilisateur
+type ParamError struct {
+	Param   string
+	Value   string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("Paramètre %s invalide (%q) : %s", e.Param, e.Value, e.Message)
+}
+
+// Construit la combinaison (ET) des filtres demandés dans les paramètres de /search, avec
+// les alternatives (OU) et les exclusions (NON) qu'ils décrivent
+func filtreDepuisRequete(q url.Values) (*Query, error) {
+	var filtres And
+	var concert SameConcert
+
+	search := strings.TrimSpace(q.Get("search"))
+	// Le champ de recherche accepte le langage décrit dans query.go (member:, country:, year:...)
+	recherche, err := analyserRecherche(search)
+	if err != nil {
+		return nil, err
+	}
+	var text *NameFilter
+	if recherche.texte != "" {
+		text = &NameFilter{Query: recherche.texte}
+		filtres = append(filtres, text)
+	}
+	filtres = append(filtres, recherche.filtres...)
+	concert = append(concert, recherche.concerts...)
+
+	// Un lieu choisi dans les suggestions ("place") remplace le texte saisi ("localisation").
+	// Plusieurs valeurs sont des alternatives : un des lieux suffit.
+	var lieux AnyConcert
+	for _, place := range q["place"] {
+		if place = strings.TrimSpace(place); place != "" {
+			lieux = append(lieux, PlaceFilter{Place: place})
+		}
+	}
+	if len(lieux) == 0 {
+		for _, location := range q["localisation"] {
+			if location = strings.TrimSpace(location); location != "" {
+				lieux = append(lieux, LocationFilter{Query: location})
+			}
+		}
+	}
+	switch len(lieux) {
+	case 0:
+	case 1:
+		concert = append(concert, lieux[0])
+	default:
+		concert = append(concert, lieux)
+	}
+
+	if date := q.Get("filtre"); date != "" {
+		parsedDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, &ParamError{Param: "filtre", Value: date, Message: "date AAAA-MM-JJ attendue"}
+		}
+		concert = append(concert, ConcertDateFilter{Date: parsedDate})
+	}
+
+	// Période de concert "from"/"to"
+	from, err := paramDate(q, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := paramDate(q, "to")
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return nil, &ParamError{Param: "from", Value: q.Get("from"), Message: "postérieure à to"}
+	}
+	if !from.IsZero() || !to.IsZero() {
+		concert = append(concert, ConcertPeriodFilter{From: from, To: to})
+	}
+
+	// Concerts autour d'un point : "near=lat,lng" et "radius_km" (100 km par défaut)
+	near, err := paramNear(q)
+	if err != nil {
+		return nil, err
+	}
+	if near != nil {
+		concert = append(concert, *near)
+	}
+
+	// Concerts à venir ou passés
+	switch when := q.Get("when"); when {
+	case "", "all":
+	case "upcoming":
+		concert = append(concert, UpcomingFilter{Upcoming: true})
+	case "past":
+		concert = append(concert, UpcomingFilter{Upcoming: false})
+	default:
+		return nil, &ParamError{Param: "when", Value: when, Message: "upcoming, past ou all attendu"}
+	}
+
+	if len(concert) > 0 {
+		filtres = append(filtres, concert)
+	}
+
+	// Année de création : "year" pour une année exacte, "year_min"/"year_max" pour une plage
+	yearMin, yearMax, err := plageEntiers(q, "year", "year_min", "year_max")
+	if err != nil {
+		return nil, err
+	}
+	if yearMin != 0 || yearMax != 0 {
+		filtres = append(filtres, CreationYearFilter{Min: yearMin, Max: yearMax})
+	}
+
+	// Nombre de membres : "members" exact ou "members_min"/"members_max"
+	membresMin, membresMax, err := plageEntiers(q, "members", "members_min", "members_max")
+	if err != nil {
+		return nil, err
+	}
+	if membresMin != 0 || membresMax != 0 {
+		filtres = append(filtres, MemberCountFilter{Min: membresMin, Max: membresMax})
+	}
+
+	// Premier album : "first_album" exact ou "album_from"/"album_to"
+	albumFrom, albumTo, err := plageDates(q, "first_album", "album_from", "album_to")
+	if err != nil {
+		return nil, err
+	}
+	if !albumFrom.IsZero() || !albumTo.IsZero() {
+		filtres = append(filtres, FirstAlbumFilter{From: albumFrom, To: albumTo})
+	}
+
+	// Exclusions, répétables : "exclure" écarte les artistes dont le nom ou un membre correspond,
+	// "exclure_localisation" ceux qui ont joué dans le lieu
+	for _, nom := range q["exclure"] {
+		if nom = strings.TrimSpace(nom); nom != "" {
+			filtres = append(filtres, Not{Filter: NameFilter{Query: nom}})
+		}
+	}
+	for _, lieu := range q["exclure_localisation"] {
+		if lieu = strings.TrimSpace(lieu); lieu != "" {
+			filtres = append(filtres, Not{Filter: LocationFilter{Query: lieu}})
+		}
+	}
+
+	return &Query{Filter: filtres, Concerts: concert, Text: text, Near: near}, nil
+}
+
+// Rayon de recherche par défaut et maximum (demi-circonférence de la Terre)
+const (
+	defaultRadiusKm = 100
+	maxRadiusKm     = 20038
+)
+
+// Lit "near=lat,lng" et "radius_km", nil si near est absent
+func paramNear(q url.Values) (*NearFilter, error) {
+	s := strings.TrimSpace(q.Get("near"))
+	if s == "" {
+		return nil, nil
+	}
+	point, err := parsePoint(s)
+	if err != nil {
+		return nil, &ParamError{Param: "near", Value: s, Message: err.Error()}
+	}
+	f := &NearFilter{Center: point, RadiusKm: defaultRadiusKm}
+	if r := strings.TrimSpace(q.Get("radius_km")); r != "" {
+		radius, err := strconv.ParseFloat(r, 64)
+		// NaN échappe à toutes les comparaisons : il est refusé explicitement, comme Inf
+		if err != nil || math.IsNaN(radius) || math.IsInf(radius, 0) || radius <= 0 || radius > maxRadiusKm {
+			return nil, &ParamError{Param: "radius_km", Value: r, Message: fmt.Sprintf("nombre de kilomètres entre 0 et %d attendu", maxRadiusKm)}
+		}
+		f.RadiusKm = radius
+	}
+	return f, nil
+}
+
+// Décode "lat,lng" en degrés décimaux
+func parsePoint(s string) (GeoPoint, error) {
+	latStr, lngStr, ok := strings.Cut(s, ",")
+	if !ok {
+		return GeoPoint{}, errors.New("coordonnées lat,lng attendues")
+	}
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	lng, err2 := strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+	// ParseFloat accepte "NaN" et "Inf", qui ne sont pas des coordonnées
+	if err1 != nil || err2 != nil || math.IsNaN(lat) || math.IsNaN(lng) || math.IsInf(lat, 0) || math.IsInf(lng, 0) {
+		return GeoPoint{}, errors.New("coordonnées lat,lng attendues")
+	}
+	if !coordonneesValides(lat, lng) {
+		return GeoPoint{}, errors.New("latitude entre -90 et 90 et longitude entre -180 et 180 attendues")
+	}
+	return GeoPoint{Lat: lat, Lng: lng}, nil
+}
+
+// Lit un paramètre entier positif, 0 s'il est absent
+func paramEntier(q url.Values, name string) (int, error) {
+	s := strings.TrimSpace(q.Get(name))
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return 0, &ParamError{Param: name, Value: s, Message: "nombre entier positif attendu"}
+	}
+	return v, nil
+}
+
+// Lit une date AAAA-MM-JJ, date zéro si elle est absente
+func paramDate(q url.Values, name string) (time.Time, error) {
+	s := strings.TrimSpace(q.Get(name))
+	if s == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, &ParamError{Param: name, Value: s, Message: "date AAAA-MM-JJ attendue"}
+	}
+	return date, nil
+}
+
+// Lit une plage d'entiers : la valeur exacte fixe les deux bornes, sinon min et max sont lus séparément
+func plageEntiers(q url.Values, exact, nameMin, nameMax string) (int, int, error) {
+	v, err := paramEntier(q, exact)
+	if err != nil || v != 0 {
+		return v, v, err
+	}
+	min, err := paramEntier(q, nameMin)
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err := paramEntier(q, nameMax)
+	if err != nil {
+		return 0, 0, err
+	}
+	if min != 0 && max != 0 && min > max {
+		return 0, 0, &ParamError{Param: nameMin, Value: q.Get(nameMin), Message: "supérieur à " + nameMax}
+	}
+	return min, max, nil
+}
+
+// Lit une plage de dates sur le même principe que plageEntiers
+func plageDates(q url.Values, exact, nameFrom, nameTo string) (time.Time, time.Time, error) {
+	v, err := paramDate(q, exact)
+	if err != nil || !v.IsZero() {
+		return v, v, err
+	}
+	from, err := paramDate(q, nameFrom)
+	if err != nil {
+		return from, from, err
+	}
+	to, err := paramDate(q, nameTo)
+	if err != nil {
+		return to, to, err
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return from, to, &ParamError{Param: nameFrom, Value: q.Get(nameFrom), Message: "postérieure à " + nameTo}
+	}
+	return from, to, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParamNear(t *testing.T) {
+	tests := []struct {
+		requete  string
+		centre   GeoPoint
+		rayon    float64
+		absent   bool
+		erreurDe string // paramètre en erreur, vide si la requête est valide
+	}{
+		{requete: "", absent: true},
+		{requete: "radius_km=50", absent: true},
+		{requete: "near=48.85,2.35", centre: GeoPoint{Lat: 48.85, Lng: 2.35}, rayon: defaultRadiusKm},
+		{requete: "near=+48.85,+2.35&radius_km=12.5", centre: GeoPoint{Lat: 48.85, Lng: 2.35}, rayon: 12.5},
+		{requete: "near=-90,180&radius_km=20038", centre: GeoPoint{Lat: -90, Lng: 180}, rayon: maxRadiusKm},
+		{requete: "near=48.85,2.35&radius_km=", centre: GeoPoint{Lat: 48.85, Lng: 2.35}, rayon: defaultRadiusKm},
+
+		// Coordonnées invalides
+		{requete: "near=48.85", erreurDe: "near"},
+		{requete: "near=nord,est", erreurDe: "near"},
+		{requete: "near=NaN,2.35", erreurDe: "near"},
+		{requete: "near=48.85,nan", erreurDe: "near"},
+		{requete: "near=Inf,2.35", erreurDe: "near"},
+		{requete: "near=48.85,-Inf", erreurDe: "near"},
+		{requete: "near=91,0", erreurDe: "near"},
+		{requete: "near=0,180.5", erreurDe: "near"},
+
+		// Rayons invalides
+		{requete: "near=48.85,2.35&radius_km=NaN", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=-nan", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=Inf", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=-Inf", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=0", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=-5", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=20038.5", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=1e9", erreurDe: "radius_km"},
+		{requete: "near=48.85,2.35&radius_km=dix", erreurDe: "radius_km"},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.requete)
+		f, err := paramNear(q)
+		if tt.erreurDe != "" {
+			var perr *ParamError
+			if !errors.As(err, &perr) || perr.Param != tt.erreurDe {
+				t.Errorf("%q : %+v, %v, attendu une erreur sur %s", tt.requete, f, err, tt.erreurDe)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q : erreur inattendue %v", tt.requete, err)
+			continue
+		}
+		if tt.absent {
+			if f != nil {
+				t.Errorf("%q : %+v, attendu aucun filtre", tt.requete, f)
+			}
+			continue
+		}
+		if f == nil || f.Center != tt.centre || f.RadiusKm != tt.rayon {
+			t.Errorf("%q : %+v, attendu centre %v et rayon %v", tt.requete, f, tt.centre, tt.rayon)
+		}
+	}
+}
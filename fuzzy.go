@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+)
+
+// Similarité minimale (entre 0 et 1) pour qu'une recherche approchée soit retenue
+const seuilSimilarite = 0.75
+
+// Structure Match : résultat d'une recherche approchée dans un texte
+type Match struct {
+	Score float64 // 1 pour une correspondance exacte, moins pour une correspondance approchée
+	Start int     // position en octets du passage correspondant dans le texte
+	End   int
+}
+
+// Structure Highlight : texte découpé autour du passage qui correspond à la recherche
+type Highlight struct {
+	Field  string // "name" ou "member"
+	Before string
+	Match  string
+	After  string
+}
+
+func surligner(field, text string, m Match) Highlight {
+	return Highlight{Field: field, Before: text[:m.Start], Match: text[m.Start:m.End], After: text[m.End:]}
+}
+
+// Mot d'un texte et sa position en octets
+type mot struct {
+	runes      []rune
+	offsets    []int // position en octets de chaque rune
+	start, end int
+}
+
+// Position en octets de la fin de la k-ième rune du mot
+func (m mot) finRune(k int) int {
+	if k+1 < len(m.offsets) {
+		return m.offsets[k+1]
+	}
+	return m.end
+}
+
+// Découpe un texte en mots normalisés (voir normaliser) en gardant leur position dans le texte d'origine
+func decouperMots(text string) []mot {
+	var mots []mot
+	var courant *mot
+	for i, r := range text {
+		forme, separateur := replierRune(r)
+		if separateur {
+			courant = nil
+			continue
+		}
+		if forme == "" {
+			if courant != nil {
+				courant.end = i + len(string(r))
+			}
+			continue
+		}
+		if courant == nil {
+			mots = append(mots, mot{start: i})
+			courant = &mots[len(mots)-1]
+		}
+		for _, f := range forme {
+			courant.runes = append(courant.runes, f)
+			courant.offsets = append(courant.offsets, i)
+		}
+		courant.end = i + len(string(r))
+	}
+	return mots
+}
+
+// Cherche query dans text : d'abord comme sous-chaîne exacte (sans tenir compte de la casse),
+// puis en tolérant des fautes de frappe mot à mot (distance d'édition).
+func rechercheApprochee(query, text string) (Match, bool) {
+	q := decouperMots(query)
+	t := decouperMots(text)
+	if len(q) == 0 {
+		return Match{}, false
+	}
+
+	// Correspondance exacte : les mots de la recherche se suivent dans le texte, chacun étant
+	// le début d'un mot du texte (le dernier pouvant apparaître n'importe où dans le mot)
+	meilleur := Match{}
+	trouve := false
+	for i := 0; i+len(q) <= len(t); i++ {
+		exact := true
+		debut, fin := t[i].start, t[i+len(q)-1].end
+		distance, longueur := 0, 0
+		for j := range q {
+			m := t[i+j]
+			if j == len(q)-1 {
+				k := indexRunes(m.runes, q[j].runes)
+				if k < 0 {
+					exact = false
+				} else {
+					// Seul le passage saisi est surligné
+					if len(q) == 1 {
+						debut = m.offsets[k]
+					}
+					fin = m.finRune(k + len(q[j].runes) - 1)
+				}
+			} else if !commencePar(m.runes, q[j].runes) {
+				exact = false
+			}
+			distance += distanceEdition(q[j].runes, m.runes)
+			longueur += max(len(q[j].runes), len(m.runes))
+		}
+		var score float64
+		if !exact {
+			debut, fin = t[i].start, t[i+len(q)-1].end
+		}
+		if exact {
+			score = 1
+			if i > 0 {
+				score = 0.95 // un début de nom est plus pertinent qu'un mot du milieu
+			}
+		} else {
+			score = 1 - float64(distance)/float64(longueur)
+			if score < seuilSimilarite {
+				continue
+			}
+			score *= 0.9 // une correspondance approchée passe après une exacte
+		}
+		if !trouve || score > meilleur.Score {
+			meilleur = Match{Score: score, Start: debut, End: fin}
+			trouve = true
+		}
+	}
+
+	// "acdc" ou "ac dc" pour "AC/DC" : le découpage en mots par la ponctuation n'est pas significatif
+	if !trouve && len(t) > 0 {
+		if compact := normaliserCompact(query); len(compact) > 1 && strings.Contains(normaliserCompact(text), compact) {
+			return Match{Score: 0.93, Start: t[0].start, End: t[len(t)-1].end}, true
+		}
+	}
+	return meilleur, trouve
+}
+
+func commencePar(texte, motif []rune) bool {
+	return len(motif) <= len(texte) && string(texte[:len(motif)]) == string(motif)
+}
+
+// Position (en runes) de motif dans texte, -1 s'il n'y apparaît pas
+func indexRunes(texte, motif []rune) int {
+	for i := 0; i+len(motif) <= len(texte); i++ {
+		if string(texte[i:i+len(motif)]) == string(motif) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Distance de Levenshtein entre deux mots
+func distanceEdition(a, b []rune) int {
+	prec := make([]int, len(b)+1)
+	cour := make([]int, len(b)+1)
+	for j := range prec {
+		prec[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cour[0] = i
+		for j := 1; j <= len(b); j++ {
+			cout := 1
+			if a[i-1] == b[j-1] {
+				cout = 0
+			}
+			cour[j] = min(prec[j]+1, cour[j-1]+1, prec[j-1]+cout)
+		}
+		prec, cour = cour, prec
+	}
+	return prec[len(b)]
+}
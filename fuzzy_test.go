@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestRechercheApprocheeSurlignage(t *testing.T) {
+	tests := []struct {
+		query, text          string
+		before, match, after string
+		scoreMin, scoreMax   float64
+	}{
+		// Correspondances exactes : seul le passage saisi est surligné
+		{"queen", "Queen", "", "Queen", "", 1, 1},
+		{"que", "Queen", "", "Que", "en", 1, 1},
+		{"mercury", "Freddie Mercury", "Freddie ", "Mercury", "", 0.95, 0.95},
+		{"ury", "Freddie Mercury", "Freddie Merc", "ury", "", 0.95, 0.95},
+		{"freddie merc", "Freddie Mercury", "", "Freddie Merc", "ury", 1, 1},
+		{"FREDDIE", "Freddie Mercury", "", "Freddie", " Mercury", 1, 1},
+
+		// Accents et ponctuation : le passage surligné garde les caractères d'origine
+		{"beyonce", "Beyoncé", "", "Beyoncé", "", 1, 1},
+		{"motorhead", "Motörhead", "", "Motörhead", "", 1, 1},
+		{"sao paulo", "São-Paulo", "", "São-Paulo", "", 1, 1},
+		{"guns n roses", "Guns N' Roses", "", "Guns N' Roses", "", 1, 1},
+
+		// Forme compacte
+		{"acdc", "AC/DC", "", "AC/DC", "", 0.93, 0.93},
+
+		// Fautes de frappe : le ou les mots entiers sont surlignés, avec un score réduit
+		{"quen", "Queen", "", "Queen", "", 0.7, 0.75},
+		{"fredie mercuri", "Freddie Mercury", "", "Freddie Mercury", "", 0.7, 0.9},
+		{"mercuri", "Freddie Mercury", "Freddie ", "Mercury", "", 0.7, 0.9},
+	}
+	for _, tt := range tests {
+		m, ok := rechercheApprochee(tt.query, tt.text)
+		if !ok {
+			t.Errorf("%q dans %q : aucune correspondance", tt.query, tt.text)
+			continue
+		}
+		h := surligner("name", tt.text, m)
+		if h.Before != tt.before || h.Match != tt.match || h.After != tt.after {
+			t.Errorf("%q dans %q : [%s|%s|%s], attendu [%s|%s|%s]",
+				tt.query, tt.text, h.Before, h.Match, h.After, tt.before, tt.match, tt.after)
+		}
+		if m.Score < tt.scoreMin || m.Score > tt.scoreMax {
+			t.Errorf("%q dans %q : score %.3f, attendu entre %.2f et %.2f", tt.query, tt.text, m.Score, tt.scoreMin, tt.scoreMax)
+		}
+	}
+}
+
+func TestRechercheApprocheeSansCorrespondance(t *testing.T) {
+	tests := []struct{ query, text string }{
+		{"zzz", "Queen"},
+		{"", "Queen"},
+		{"!!", "Queen"},
+		{"metallica", "Queen"},
+		{"pink floyd", "Pink"},
+	}
+	for _, tt := range tests {
+		if m, ok := rechercheApprochee(tt.query, tt.text); ok {
+			t.Errorf("%q dans %q : correspondance inattendue %+v", tt.query, tt.text, m)
+		}
+	}
+}
+
+func TestDistanceEdition(t *testing.T) {
+	tests := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"queen", "queen", 0},
+		{"quen", "queen", 1},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"motörhead", "motorhead", 1},
+	}
+	for _, tt := range tests {
+		if d := distanceEdition([]rune(tt.a), []rune(tt.b)); d != tt.d {
+			t.Errorf("distance(%q, %q) = %d, attendu %d", tt.a, tt.b, d, tt.d)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"embed"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Gazetteer embarqué dans le binaire : coordonnées de chaque lieu de concert connu, sans réseau.
+//
+//	gazetteer/countries.csv : partie pays des clés de l'API ("uk"), nom canonique, code ISO 3166-1 et continent
+//	gazetteer/places.csv    : clé "ville-pays" de l'API, nom canonique, latitude et longitude
+//
+//go:embed gazetteer/*.csv
+var fichiersGazetteer embed.FS
+
+// Structure GeoPoint : coordonnées en degrés décimaux (WGS 84)
+type GeoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+// Structure GeoCountry : pays du gazetteer
+type GeoCountry struct {
+	Name      string
+	ISO       string // code ISO 3166-1 alpha-2
+	Continent string // AF, AN, AS, EU, NA, OC ou SA
+}
+
+// Structure GeoPlace : lieu de concert résolu par le gazetteer
+type GeoPlace struct {
+	GeoPoint
+	Key     string // clé "ville-pays" du gazetteer
+	Name    string // nom canonique de la ville (ou de la région)
+	Country GeoCountry
+}
+
+// Structure Gazetteer : lieux et pays indexés par clé normalisée
+type Gazetteer struct {
+	lieux map[string]*GeoPlace
+	pays  map[string]GeoCountry
+}
+
+// Gazetteer chargé au démarrage depuis les fichiers embarqués
+var gazetteer = mustChargerGazetteer()
+
+func mustChargerGazetteer() *Gazetteer {
+	g, err := chargerGazetteer(fichiersGazetteer)
+	if err != nil {
+		panic(fmt.Sprintf("gazetteer embarqué invalide : %v", err))
+	}
+	return g
+}
+
+// Lit les fichiers countries.csv et places.csv du dossier gazetteer/
+func chargerGazetteer(fsys fs.FS) (*Gazetteer, error) {
+	g := &Gazetteer{lieux: make(map[string]*GeoPlace), pays: make(map[string]GeoCountry)}
+
+	err := lireCSV(fsys, "gazetteer/countries.csv", 4, func(ligne []string) error {
+		cle := normaliser(ligne[0])
+		if _, ok := g.pays[cle]; ok {
+			return errors.New("pays en double")
+		}
+		if len(ligne[2]) != 2 {
+			return fmt.Errorf("code ISO %q invalide", ligne[2])
+		}
+		g.pays[cle] = GeoCountry{Name: ligne[1], ISO: ligne[2], Continent: ligne[3]}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = lireCSV(fsys, "gazetteer/places.csv", 4, func(ligne []string) error {
+		cle := cleLieuNormalisee(ligne[0], false)
+		if _, ok := g.lieux[cle]; ok {
+			return errors.New("lieu en double")
+		}
+		pays, ok := g.pays[cleLieuNormalisee(ligne[0], true)]
+		if !ok {
+			return fmt.Errorf("pays de %q absent de countries.csv", ligne[0])
+		}
+		lat, err1 := strconv.ParseFloat(ligne[2], 64)
+		lng, err2 := strconv.ParseFloat(ligne[3], 64)
+		if err1 != nil || err2 != nil || !coordonneesValides(lat, lng) {
+			return fmt.Errorf("coordonnées %s,%s invalides", ligne[2], ligne[3])
+		}
+		g.lieux[cle] = &GeoPlace{GeoPoint: GeoPoint{Lat: lat, Lng: lng}, Key: ligne[0], Name: ligne[1], Country: pays}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Lit un fichier CSV avec une ligne d'en-tête et passe chaque ligne à traiter
+func lireCSV(fsys fs.FS, nom string, colonnes int, traiter func([]string) error) error {
+	f, err := fsys.Open(nom)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = colonnes
+	if _, err := r.Read(); err != nil {
+		return fmt.Errorf("%s : %w", nom, err)
+	}
+	for {
+		ligne, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s : %w", nom, err)
+		}
+		if err := traiter(ligne); err != nil {
+			num, _ := r.FieldPos(0)
+			return fmt.Errorf("%s ligne %d : %w", nom, num, err)
+		}
+	}
+}
+
+// Rayon moyen de la Terre en kilomètres
+const rayonTerreKm = 6371.0088
+
+// Distance orthodromique entre deux points (formule de haversine)
+func distanceKm(a, b GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * rayonTerreKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func coordonneesValides(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// Lieu correspondant à une clé "ville-pays" de l'API, nil s'il est inconnu.
+// La clé est comparée normalisée : "são_paulo-brazil" est résolu comme "sao_paulo-brazil".
+func (g *Gazetteer) Resolve(key string) *GeoPlace {
+	return g.lieux[cleLieuNormalisee(key, false)]
+}
+
+// Pays correspondant à la partie pays d'une clé de l'API
+func (g *Gazetteer) Country(key string) (GeoCountry, bool) {
+	c, ok := g.pays[cleLieuNormalisee(key, strings.Contains(key, "-"))]
+	return c, ok
+}
+
+// Nombre de lieux connus
+func (g *Gazetteer) Len() int {
+	return len(g.lieux)
+}
+
+// Associe ses coordonnées à chaque lieu et à chaque concert. Renvoie les clés triées que le
+// gazetteer ne connaît pas.
+func geocoder(g *Gazetteer, model []*Artist, places []Location) []string {
+	inconnus := make(map[string]bool)
+	for i := range places {
+		places[i].Geo = g.Resolve(places[i].Key)
+		if places[i].Geo == nil {
+			inconnus[places[i].Key] = true
+		}
+	}
+	for _, a := range model {
+		for i := range a.Concerts {
+			a.Concerts[i].Geo = g.Resolve(a.Concerts[i].Location)
+			if a.Concerts[i].Geo == nil {
+				inconnus[a.Concerts[i].Location] = true
+			}
+		}
+	}
+	cles := make([]string, 0, len(inconnus))
+	for cle := range inconnus {
+		cles = append(cles, cle)
+	}
+	sort.Strings(cles)
+	return cles
+}
+
+// Sous-commande "gazetteer" : vérifie que tous les lieux de concert ont des coordonnées.
+// Utilise le dossier -data s'il est fourni, l'API sinon. Renvoie une erreur si des lieux sont inconnus.
+func gazetteerCommand(args []string) error {
+	flags := flag.NewFlagSet("gazetteer", flag.ExitOnError)
+	dataDir := flags.String("data", "", "dossier contenant les fichiers JSON des données (API si vide)")
+	flags.Parse(args)
+
+	d, err := chargerDataset(choisirSource(*dataDir))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d lieux de concert, %d lieux dans le gazetteer\n", len(d.Places), gazetteer.Len())
+	if len(d.Unresolved) == 0 {
+		fmt.Println("Tous les lieux sont résolus")
+		return nil
+	}
+	for _, cle := range d.Unresolved {
+		fmt.Println(" -", cle)
+	}
+	return fmt.Errorf("%d lieu(x) absent(s) du gazetteer", len(d.Unresolved))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Chaque lieu du flux de l'API doit avoir des coordonnées et un pays : un lieu manquant
+// disparaît silencieusement de la carte, de la recherche par distance et des tournées
+func TestGazetteerCouvreLieuxAPI(t *testing.T) {
+	f, err := os.Open("testdata/upstream_locations.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n := 0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		cle := strings.TrimSpace(sc.Text())
+		if cle == "" || strings.HasPrefix(cle, "#") {
+			continue
+		}
+		n++
+		if gazetteer.Resolve(cle) == nil {
+			t.Errorf("%s : absent de gazetteer/places.csv", cle)
+		}
+		if _, ok := gazetteer.Country(cle); !ok {
+			t.Errorf("%s : pays absent de gazetteer/countries.csv", cle)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("aucune clé dans testdata/upstream_locations.txt")
+	}
+}
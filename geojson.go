@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// Types GeoJSON (RFC 7946) renvoyés par /api/v1/concerts.geojson et /api/v1/artists/{id}/tour.geojson.
+// Les positions sont au format [longitude, latitude].
+type FeatureCollection struct {
+	Type       string    `json:"type"`
+	Features   []Feature `json:"features"`
+	Unresolved []string  `json:"unresolved,omitempty"` // lieux absents du gazetteer, donc sans point
+}
+
+type Feature struct {
+	Type       string   `json:"type"`
+	Geometry   Geometry `json:"geometry"`
+	Properties any      `json:"properties"`
+}
+
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// Propriétés d'un point de concert
+type ConcertProperties struct {
+	Kind        string `json:"kind"` // "concert"
+	ArtistID    int    `json:"artistId"`
+	ArtistName  string `json:"artistName"`
+	Date        string `json:"date"`
+	Upcoming    bool   `json:"upcoming"`
+	Location    string `json:"location"`
+	City        string `json:"city"`
+	Country     string `json:"country"`
+	CountryCode string `json:"countryCode"`
+}
+
+// Propriétés de la ligne d'une tournée
+type TourProperties struct {
+	Kind         string `json:"kind"` // "tour"
+	ArtistID     int    `json:"artistId"`
+	ArtistName   string `json:"artistName"`
+	ConcertCount int    `json:"concertCount"`
+	Start        string `json:"start"`
+	End          string `json:"end"`
+}
+
+func position(p GeoPoint) [2]float64 {
+	return [2]float64{p.Lng, p.Lat}
+}
+
+// Ajoute à la collection un point par concert géocodé puis, s'il y a au moins deux lieux, la
+// ligne de la tournée dans l'ordre des dates (les concerts sont déjà triés par date)
+func (fc *FeatureCollection) ajouterTournee(a *Artist, concerts []Concert, inconnus map[string]bool) {
+	var geocodes []Concert
+	for _, c := range concerts {
+		if c.Geo == nil {
+			inconnus[c.Location] = true
+			continue
+		}
+		geocodes = append(geocodes, c)
+		fc.Features = append(fc.Features, Feature{
+			Type:     "Feature",
+			Geometry: Geometry{Type: "Point", Coordinates: position(c.Geo.GeoPoint)},
+			Properties: ConcertProperties{
+				Kind:        "concert",
+				ArtistID:    a.ID,
+				ArtistName:  a.Name,
+				Date:        c.Date.Format("2006-01-02"),
+				Upcoming:    c.Upcoming,
+				Location:    c.Location,
+				City:        c.City,
+				Country:     c.Country,
+				CountryCode: c.Geo.Country.ISO,
+			},
+		})
+	}
+
+	// Deux concerts de suite au même endroit ne forment qu'un point de la ligne
+	var points []GeoPoint
+	for _, c := range geocodes {
+		if len(points) == 0 || points[len(points)-1] != c.Geo.GeoPoint {
+			points = append(points, c.Geo.GeoPoint)
+		}
+	}
+	if len(points) < 2 {
+		return
+	}
+	fc.Features = append(fc.Features, Feature{
+		Type:     "Feature",
+		Geometry: ligneTournee(points),
+		Properties: TourProperties{
+			Kind:         "tour",
+			ArtistID:     a.ID,
+			ArtistName:   a.Name,
+			ConcertCount: len(geocodes),
+			Start:        geocodes[0].Date.Format("2006-01-02"),
+			End:          geocodes[len(geocodes)-1].Date.Format("2006-01-02"),
+		},
+	})
+}
+
+// LineString de la tournée, coupée en MultiLineString à l'antiméridien comme le demande la
+// RFC 7946 : un trajet Papeete - Nouméa ne traverse pas toute la carte.
+func ligneTournee(points []GeoPoint) Geometry {
+	var lignes [][][2]float64
+	courante := [][2]float64{position(points[0])}
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		if math.Abs(b.Lng-a.Lng) > 180 {
+			// Longitude de b déroulée du même côté que a, puis latitude du passage à ±180
+			bLng := b.Lng + 360
+			bord := 180.0
+			if a.Lng < 0 {
+				bLng, bord = b.Lng-360, -180
+			}
+			lat := a.Lat + (b.Lat-a.Lat)*(bord-a.Lng)/(bLng-a.Lng)
+			courante = append(courante, [2]float64{bord, lat})
+			lignes = append(lignes, courante)
+			courante = [][2]float64{{-bord, lat}}
+		}
+		courante = append(courante, position(b))
+	}
+	lignes = append(lignes, courante)
+	if len(lignes) == 1 {
+		return Geometry{Type: "LineString", Coordinates: lignes[0]}
+	}
+	return Geometry{Type: "MultiLineString", Coordinates: lignes}
+}
+
+// Termine la collection et l'écrit avec le type de contenu GeoJSON
+func ecrireGeoJSON(w http.ResponseWriter, fc FeatureCollection, inconnus map[string]bool) {
+	for cle := range inconnus {
+		fc.Unresolved = append(fc.Unresolved, cle)
+	}
+	sort.Strings(fc.Unresolved)
+	if fc.Features == nil {
+		fc.Features = []Feature{}
+	}
+	w.Header().Set("Content-Type", "application/geo+json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fc); err != nil {
+		log.Printf("Erreur lors de l'encodage GeoJSON : %v\n", err)
+	}
+}
+
+// /api/v1/concerts.geojson : concerts des artistes trouvés (mêmes paramètres que /api/v1/concerts),
+// avec la tournée de chaque artiste
+func apiConcertsGeoJSON(w http.ResponseWriter, r *http.Request, d *Dataset) {
+	Results, ok := resultatsDepuisRequete(w, r, d)
+	if !ok {
+		return
+	}
+	fc := FeatureCollection{Type: "FeatureCollection"}
+	inconnus := make(map[string]bool)
+	for _, res := range Results {
+		fc.ajouterTournee(res.Artist, res.RelevantConcerts(), inconnus)
+	}
+	ecrireGeoJSON(w, fc, inconnus)
+}
+
+// /api/v1/artists/{id}/tour.geojson : tournée d'un artiste (filtrable comme /api/v1/artists/{id}/concerts)
+func apiTourGeoJSON(w http.ResponseWriter, r *http.Request, d *Dataset, idstr string) {
+	artist := artisteDepuisURL(w, d, idstr)
+	if artist == nil {
+		return
+	}
+	query, err := filtreDepuisRequete(r.URL.Query())
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	var concerts []Concert
+	for _, c := range artist.Concerts {
+		if len(query.Concerts) == 0 || query.Concerts.MatchConcert(c) {
+			concerts = append(concerts, c)
+		}
+	}
+	fc := FeatureCollection{Type: "FeatureCollection"}
+	inconnus := make(map[string]bool)
+	fc.ajouterTournee(artist, concerts, inconnus)
+	ecrireGeoJSON(w, fc, inconnus)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Longueur maximale d'une ligne iCalendar en octets, hors CRLF (RFC 5545, 3.1)
+const longueurLigneICS = 75
+
+// Adresse publique du site (flag -public-url) pour le lien URL de chaque événement. Les clients
+// abonnés gardent ce lien en cache : il ne vient jamais de l'en-tête Host, que la requête peut
+// falsifier. Vide, les événements n'ont pas de lien ; l'UID suffit à les identifier.
+var urlPublique string
+
+// Vérifie l'adresse donnée à -public-url : http ou https, un hôte, sans paramètres.
+// Renvoie l'adresse sans "/" final.
+func analyserURLPublique(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("adresse http(s)://hôte[/chemin] attendue (reçu %q)", s)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// Structure calendrier : écrit un flux iCalendar avec des fins de ligne CRLF et des lignes repliées
+type calendrier struct {
+	buf bytes.Buffer
+}
+
+// Écrit une ligne de contenu, repliée tous les 75 octets sans couper de caractère UTF-8
+func (c *calendrier) ligne(nom, valeur string) {
+	s := nom + ":" + valeur
+	limite := longueurLigneICS
+	for len(s) > limite {
+		coupe := limite
+		for coupe > 0 && !utf8.RuneStart(s[coupe]) {
+			coupe--
+		}
+		c.buf.WriteString(s[:coupe])
+		c.buf.WriteString("\r\n ")
+		s = s[coupe:]
+		// L'espace de continuation compte dans la ligne suivante
+		limite = longueurLigneICS - 1
+	}
+	c.buf.WriteString(s)
+	c.buf.WriteString("\r\n")
+}
+
+// Échappe une valeur de type TEXT
+func texteICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// Calendrier des concerts donnés : un VEVENT d'une journée par concert
+func ecrireCalendrier(w http.ResponseWriter, d *Dataset, nom, fichier string, concerts []concertArtiste) {
+	var c calendrier
+	c.ligne("BEGIN", "VCALENDAR")
+	c.ligne("VERSION", "2.0")
+	c.ligne("PRODID", "-//Groupie Tracker//Concerts//FR")
+	c.ligne("CALSCALE", "GREGORIAN")
+	c.ligne("METHOD", "PUBLISH")
+	c.ligne("X-WR-CALNAME", texteICS(nom))
+
+	// DTSTAMP : date du chargement des données, pour que l'export ne change pas entre deux rafraîchissements
+	stamp := d.LoadedAt.UTC().Format("20060102T150405Z")
+	for _, ca := range concerts {
+		a, co := ca.artist, ca.concert
+		c.ligne("BEGIN", "VEVENT")
+		c.ligne("UID", texteICS(uidConcert(a, co)))
+		c.ligne("DTSTAMP", stamp)
+		c.ligne("DTSTART;VALUE=DATE", co.Date.Format("20060102"))
+		c.ligne("DTEND;VALUE=DATE", co.Date.AddDate(0, 0, 1).Format("20060102"))
+		c.ligne("SUMMARY", texteICS(a.Name+" en concert à "+co.City))
+		c.ligne("LOCATION", texteICS(co.City+", "+co.Country))
+		if co.Geo != nil {
+			c.ligne("GEO", strconv.FormatFloat(co.Geo.Lat, 'f', -1, 64)+";"+strconv.FormatFloat(co.Geo.Lng, 'f', -1, 64))
+		}
+		if urlPublique != "" {
+			c.ligne("URL", urlPublique+"/artist/"+strconv.Itoa(a.ID))
+		}
+		c.ligne("TRANSP", "TRANSPARENT")
+		c.ligne("END", "VEVENT")
+	}
+	c.ligne("END", "VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fichier))
+	c.buf.WriteTo(w)
+}
+
+// Identifiant stable d'un concert : un artiste ne joue qu'une fois par jour dans un lieu
+func uidConcert(a *Artist, c Concert) string {
+	return fmt.Sprintf("%d-%s-%s@groupie-tracker", a.ID, c.Date.Format("20060102"), c.Location)
+}
+
+// Concert accompagné de son artiste
+type concertArtiste struct {
+	artist  *Artist
+	concert Concert
+}
+
+// /artist/{id}/concerts.ics : concerts d'un artiste, filtrables comme /api/v1/artists/{id}/concerts
+// (par exemple ?when=upcoming pour s'abonner aux seuls concerts à venir)
+func artistCalendarHandler(w http.ResponseWriter, r *http.Request, d *Dataset, artist *Artist) {
+	query, err := filtreDepuisRequete(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var concerts []concertArtiste
+	for _, c := range artist.Concerts {
+		if len(query.Concerts) == 0 || query.Concerts.MatchConcert(c) {
+			concerts = append(concerts, concertArtiste{artist, c})
+		}
+	}
+	ecrireCalendrier(w, d, artist.Name+" - concerts", "concerts-"+strconv.Itoa(artist.ID)+".ics", concerts)
+}
+
+// /search.ics : concerts des artistes trouvés, avec les mêmes paramètres que /search
+func searchCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	d := datasetPourRequete(w)
+	if d == nil {
+		return
+	}
+	Results, err := rechercher(d, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var concerts []concertArtiste
+	for _, res := range Results {
+		for _, c := range res.RelevantConcerts() {
+			concerts = append(concerts, concertArtiste{res.Artist, c})
+		}
+	}
+	// Ordre chronologique, indépendant du tri des résultats
+	sort.SliceStable(concerts, func(i, j int) bool {
+		return concerts[i].concert.Date.Before(concerts[j].concert.Date)
+	})
+	ecrireCalendrier(w, d, "Groupie Tracker - recherche", "concerts.ics", concerts)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Champs de l'index inversé
+const (
+	champNom    = "name"
+	champMembre = "member"
+	champVille  = "city"
+	champPays   = "country"
+	champAnnee  = "year"
+	champAlbum  = "album" // année du premier album
+	champLieu   = "place" // mots des lieux de concert, renvoie des positions dans Dataset.Places
+)
+
+// Structure InvertedIndex : pour chaque champ, chaque mot normalisé renvoie la liste triée des
+// artistes (ou des lieux pour champLieu) où il apparaît. Construit avec le Dataset et remplacé avec lui.
+type InvertedIndex struct {
+	postings map[string]map[string][]int
+	termes   map[string][][]string // vocabulaire de chaque champ rangé par longueur en runes, trié
+}
+
+// Ensemble d'identifiants renvoyé par l'index
+type idSet map[int]bool
+
+func (s idSet) intersection(autre idSet) idSet {
+	res := idSet{}
+	for id := range s {
+		if autre[id] {
+			res[id] = true
+		}
+	}
+	return res
+}
+
+func (s idSet) union(autre idSet) {
+	for id := range autre {
+		s[id] = true
+	}
+}
+
+// Identifiants de l'ensemble dans l'ordre croissant
+func (s idSet) tries() []int {
+	ids := make([]int, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func construireIndex(model []*Artist, places []Location) *InvertedIndex {
+	ix := &InvertedIndex{
+		postings: make(map[string]map[string][]int),
+		termes:   make(map[string][][]string),
+	}
+	for _, a := range model {
+		ix.ajouterTexte(champNom, a.Name, a.ID)
+		if !a.FirstAlbum.IsZero() {
+			ix.ajouter(champAlbum, strconv.Itoa(a.FirstAlbum.Year()), a.ID)
+		}
+		for _, membre := range a.Members {
+			ix.ajouterTexte(champMembre, membre, a.ID)
+		}
+		for _, c := range a.Concerts {
+			ville, pays, _ := strings.Cut(c.Location, "-")
+			ix.ajouterTexte(champVille, ville, a.ID)
+			ix.ajouterTexte(champPays, pays, a.ID)
+			ix.ajouter(champAnnee, strconv.Itoa(c.Date.Year()), a.ID)
+		}
+	}
+	for i, loc := range places {
+		ix.ajouterTexte(champLieu, loc.City, i)
+		ix.ajouterTexte(champLieu, loc.Country, i)
+		// Forme compacte du libellé "Ville, Pays" des suggestions ("parisfr")
+		ix.ajouter(champLieu, normaliserCompact(loc.City+loc.Country), i)
+	}
+
+	// Tri et dédoublonnage des listes, vocabulaire rangé par longueur puis trié
+	for champ, parTerme := range ix.postings {
+		var termes [][]string
+		for terme, ids := range parTerme {
+			sort.Ints(ids)
+			parTerme[terme] = compacterIDs(ids)
+			n := utf8.RuneCountInString(terme)
+			for len(termes) <= n {
+				termes = append(termes, nil)
+			}
+			termes[n] = append(termes[n], terme)
+		}
+		for _, groupe := range termes {
+			sort.Strings(groupe)
+		}
+		ix.termes[champ] = termes
+	}
+	return ix
+}
+
+// Indexe chaque mot normalisé du texte, ainsi que le texte sans espaces ("acdc" pour "AC/DC")
+func (ix *InvertedIndex) ajouterTexte(champ, texte string, id int) {
+	for _, mot := range strings.Fields(normaliser(texte)) {
+		ix.ajouter(champ, mot, id)
+	}
+	if compact := normaliserCompact(texte); compact != "" {
+		ix.ajouter(champ, compact, id)
+	}
+}
+
+func (ix *InvertedIndex) ajouter(champ, terme string, id int) {
+	if ix.postings[champ] == nil {
+		ix.postings[champ] = make(map[string][]int)
+	}
+	ix.postings[champ][terme] = append(ix.postings[champ][terme], id)
+}
+
+func compacterIDs(ids []int) []int {
+	res := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// Termes du champ qui contiennent mot, ou qui lui ressemblent à au moins `similarite` près
+// (0 pour ne garder que les termes qui le contiennent)
+func (ix *InvertedIndex) TermesApprox(champ, mot string, similarite float64) []string {
+	var res []string
+	motRunes := []rune(mot)
+	n := len(motRunes)
+	// Un terme qui contient mot est au moins aussi long. La distance d'édition étant au moins
+	// égale à la différence de longueur, un terme ressemblant a entre n*similarite et
+	// n/similarite runes : seuls ces groupes passent par distanceEdition.
+	debut, fin := n, n
+	if similarite > 0 {
+		debut, fin = int(float64(n)*similarite), int(math.Ceil(float64(n)/similarite))
+	}
+	groupes := ix.termes[champ]
+	for l := debut; l < len(groupes); l++ {
+		for _, terme := range groupes[l] {
+			ok := l >= n && strings.Contains(terme, mot)
+			if !ok && similarite > 0 && l <= fin {
+				termeRunes := []rune(terme)
+				longueur := max(n, l)
+				if 1-float64(abs(n-l))/float64(longueur) >= similarite {
+					ok = 1-float64(distanceEdition(motRunes, termeRunes))/float64(longueur) >= similarite
+				}
+			}
+			if ok {
+				res = append(res, terme)
+			}
+		}
+	}
+	return res
+}
+
+// Identifiants associés aux termes renvoyés par TermesApprox
+func (ix *InvertedIndex) Approx(champ, mot string, similarite float64) idSet {
+	res := idSet{}
+	for _, terme := range ix.TermesApprox(champ, mot, similarite) {
+		for _, id := range ix.postings[champ][terme] {
+			res[id] = true
+		}
+	}
+	return res
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Artistes susceptibles de correspondre à une recherche textuelle sur le nom ou les membres
+// (sur-ensemble des artistes retenus par rechercheApprochee, qui reste le juge final)
+func (ix *InvertedIndex) CandidatsTexte(query string) idSet {
+	mots := strings.Fields(normaliser(query))
+	res := idSet{}
+	if len(mots) == 0 {
+		return res
+	}
+
+	// Correspondance exacte : chaque mot est contenu dans un mot du nom ou d'un membre
+	var exact idSet
+	for _, mot := range mots {
+		trouves := ix.Approx(champNom, mot, 0)
+		trouves.union(ix.Approx(champMembre, mot, 0))
+		if exact == nil {
+			exact = trouves
+		} else {
+			exact = exact.intersection(trouves)
+		}
+	}
+	res.union(exact)
+
+	// Correspondance approchée : la similarité globale étant une moyenne pondérée de celle des
+	// mots, au moins un mot atteint le seuil
+	for _, mot := range mots {
+		res.union(ix.Approx(champNom, mot, seuilSimilarite))
+		res.union(ix.Approx(champMembre, mot, seuilSimilarite))
+	}
+
+	// Recherche sans tenir compte de la ponctuation ("acdc")
+	compact := normaliserCompact(query)
+	res.union(ix.Approx(champNom, compact, 0))
+	res.union(ix.Approx(champMembre, compact, 0))
+	return res
+}
+
+// Artistes ayant joué dans un lieu dont les mots contiennent tous ceux de la recherche
+func (ix *InvertedIndex) CandidatsLieu(query string) idSet {
+	var res idSet
+	for _, mot := range strings.Fields(normaliser(query)) {
+		trouves := ix.Approx(champVille, mot, 0)
+		trouves.union(ix.Approx(champPays, mot, 0))
+		if res == nil {
+			res = trouves
+		} else {
+			res = res.intersection(trouves)
+		}
+	}
+	return res
+}
+
+// Artistes ayant joué au moins une fois entre les années debut et fin incluses
+func (ix *InvertedIndex) CandidatsAnnees(debut, fin int) idSet {
+	res := idSet{}
+	for terme := range ix.postings[champAnnee] {
+		annee, _ := strconv.Atoi(terme)
+		if annee >= debut && annee <= fin {
+			for _, id := range ix.postings[champAnnee][terme] {
+				res[id] = true
+			}
+		}
+	}
+	return res
+}
+
+// Positions dans Dataset.Places des lieux dont la ville ou le pays contient tous les mots de la
+// recherche, ou sa forme compacte (sur-ensemble des lieux retenus par scoreSuggestion)
+func (ix *InvertedIndex) CandidatsPlaces(query string) idSet {
+	var res idSet
+	for _, mot := range strings.Fields(normaliser(query)) {
+		trouves := ix.Approx(champLieu, mot, 0)
+		if res == nil {
+			res = trouves
+		} else {
+			res = res.intersection(trouves)
+		}
+	}
+	if res == nil {
+		return idSet{}
+	}
+	if compact := normaliserCompact(query); compact != "" {
+		res.union(ix.Approx(champLieu, compact, 0))
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Dataset synthétique de n artistes, aux noms et membres tirés de syllabes et aux concerts
+// répartis sur tous les lieux du gazetteer. Le tirage est fixe d'une exécution à l'autre.
+func datasetSynthetique(tb testing.TB, n int) *Dataset {
+	tb.Helper()
+	var lieux []string
+	err := lireCSV(fichiersGazetteer, "gazetteer/places.csv", 4, func(ligne []string) error {
+		lieux = append(lieux, ligne[0])
+		return nil
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	syllabes := []string{"ka", "lo", "mer", "fre", "di", "que", "en", "pin", "flo", "yd", "ro", "ger", "ta", "ly", "son", "bri", "an", "ma", "ay", "zo"}
+	mot := func() string {
+		var b strings.Builder
+		for i := 0; i < 2+r.Intn(3); i++ {
+			b.WriteString(syllabes[r.Intn(len(syllabes))])
+		}
+		s := b.String()
+		return strings.ToUpper(s[:1]) + s[1:]
+	}
+	date := func(debut int) string {
+		return fmt.Sprintf("%02d-%02d-%d", 1+r.Intn(28), 1+r.Intn(12), debut+r.Intn(2025-debut))
+	}
+
+	var (
+		artists   []ArtistsInfo
+		locations LocationsInfo
+		dates     DatesInfo
+		relations RelationsInfo
+	)
+	for id := 1; id <= n; id++ {
+		a := ArtistsInfo{ID: id, Name: mot() + " " + mot(), CreationDate: 1950 + r.Intn(70), FirstAlbum: date(1955)}
+		for i := 0; i < 1+r.Intn(6); i++ {
+			a.Members = append(a.Members, mot()+" "+mot())
+		}
+		loc := LocationIndex{ID: id}
+		dat := DateIndex{ID: id}
+		rel := RelationIndex{ID: id, DatesLocations: make(map[string][]string)}
+		for i := 0; i < 1+r.Intn(12); i++ {
+			lieu, jour := lieux[r.Intn(len(lieux))], date(1960)
+			loc.Locations = append(loc.Locations, lieu)
+			dat.Dates = append(dat.Dates, jour)
+			rel.DatesLocations[lieu] = append(rel.DatesLocations[lieu], jour)
+		}
+		artists = append(artists, a)
+		locations.Index = append(locations.Index, loc)
+		dates.Index = append(dates.Index, dat)
+		relations.Index = append(relations.Index, rel)
+	}
+	return newDataset(artists, locations, dates, relations)
+}
+
+// Le rangement du vocabulaire par longueur ne doit écarter aucun terme : TermesApprox renvoie
+// les mêmes termes qu'un parcours complet du vocabulaire
+func TestTermesApproxGroupes(t *testing.T) {
+	ix := datasetSynthetique(t, 300).Index
+	for _, champ := range []string{champNom, champMembre, champLieu} {
+		var vocabulaire []string
+		for _, groupe := range ix.termes[champ] {
+			vocabulaire = append(vocabulaire, groupe...)
+		}
+		for _, mot := range []string{"", "q", "que", "fre", "kalo", "merdi", "paris", "sanfran", "kalomerta", "brianmazo"} {
+			for _, similarite := range []float64{0, 0.5, seuilSimilarite, 0.9, 1} {
+				attendus := make(map[string]bool)
+				motRunes := []rune(mot)
+				for _, terme := range vocabulaire {
+					termeRunes := []rune(terme)
+					longueur := max(len(motRunes), len(termeRunes))
+					if strings.Contains(terme, mot) || similarite > 0 && 1-float64(distanceEdition(motRunes, termeRunes))/float64(longueur) >= similarite {
+						attendus[terme] = true
+					}
+				}
+				obtenus := ix.TermesApprox(champ, mot, similarite)
+				trouves := make(map[string]bool)
+				for _, terme := range obtenus {
+					trouves[terme] = true
+				}
+				if len(obtenus) != len(trouves) || !reflect.DeepEqual(trouves, attendus) {
+					t.Errorf("%s %q à %v : %d termes, attendu %d", champ, mot, similarite, len(obtenus), len(attendus))
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkRecherche(b *testing.B) {
+	d := datasetSynthetique(b, 5000)
+	for _, requete := range []string{
+		"search=freddie",
+		"search=fredy+merkury",
+		"search=city:paris+year:1990..2000",
+		"localisation=new+york&members_min=3",
+	} {
+		q, _ := url.ParseQuery(requete)
+		b.Run(requete, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := rechercher(d, q); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSuggestions(b *testing.B) {
+	d := datasetSynthetique(b, 5000)
+	for _, saisie := range []string{"q", "que", "frediq", "paris", "19"} {
+		b.Run(saisie, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				suggestions(d, saisie)
+			}
+		})
+	}
+}
+
+func BenchmarkSuggestionsLieux(b *testing.B) {
+	d := datasetSynthetique(b, 5000)
+	for _, saisie := range []string{"s", "san", "new york", "germ"} {
+		b.Run(saisie, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				suggestionsLieux(d, saisie)
+			}
+		})
+	}
+}
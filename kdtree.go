@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Structure KDTree : arbre k-d des lieux de concert géocodés, pour trouver le lieu le plus proche
+// d'un point. Les points sont placés sur la sphère unité en coordonnées cartésiennes : la distance
+// euclidienne (corde) varie comme la distance orthodromique, y compris de part et d'autre de
+// l'antiméridien. Construit avec le Dataset et remplacé avec lui.
+type KDTree struct {
+	racine *kdNoeud
+	places []Location
+}
+
+type kdNoeud struct {
+	point         [3]float64
+	place         int // position dans KDTree.places
+	axe           int
+	gauche, droit *kdNoeud
+}
+
+// Position d'un point sur la sphère unité
+func versCartesien(p GeoPoint) [3]float64 {
+	lat, lng := p.Lat*math.Pi/180, p.Lng*math.Pi/180
+	return [3]float64{math.Cos(lat) * math.Cos(lng), math.Cos(lat) * math.Sin(lng), math.Sin(lat)}
+}
+
+func distanceCarree(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// Construit l'arbre à partir des lieux qui ont des coordonnées
+func construireKDTree(places []Location) *KDTree {
+	t := &KDTree{places: places}
+	noeuds := make([]*kdNoeud, 0, len(places))
+	for i, p := range places {
+		if p.Geo != nil {
+			noeuds = append(noeuds, &kdNoeud{point: versCartesien(p.Geo.GeoPoint), place: i})
+		}
+	}
+	t.racine = construireNoeud(noeuds, 0)
+	return t
+}
+
+// Coupe la liste sur la médiane de l'axe courant, puis sur l'axe suivant à chaque niveau
+func construireNoeud(noeuds []*kdNoeud, profondeur int) *kdNoeud {
+	if len(noeuds) == 0 {
+		return nil
+	}
+	axe := profondeur % 3
+	sort.Slice(noeuds, func(i, j int) bool { return noeuds[i].point[axe] < noeuds[j].point[axe] })
+	milieu := len(noeuds) / 2
+	n := noeuds[milieu]
+	n.axe = axe
+	n.gauche = construireNoeud(noeuds[:milieu], profondeur+1)
+	n.droit = construireNoeud(noeuds[milieu+1:], profondeur+1)
+	return n
+}
+
+// Lieu le plus proche du point et sa distance en kilomètres. ok est faux si aucun lieu n'a de coordonnées.
+func (t *KDTree) Nearest(p GeoPoint) (place Location, km float64, ok bool) {
+	if t == nil || t.racine == nil {
+		return Location{}, 0, false
+	}
+	cible := versCartesien(p)
+	var meilleur *kdNoeud
+	meilleure := math.Inf(1)
+
+	var chercher func(n *kdNoeud)
+	chercher = func(n *kdNoeud) {
+		if n == nil {
+			return
+		}
+		if d := distanceCarree(n.point, cible); d < meilleure {
+			meilleur, meilleure = n, d
+		}
+		ecart := cible[n.axe] - n.point[n.axe]
+		proche, loin := n.gauche, n.droit
+		if ecart > 0 {
+			proche, loin = n.droit, n.gauche
+		}
+		chercher(proche)
+		// L'autre côté ne peut contenir un point plus proche que si le plan de coupe l'est
+		if ecart*ecart < meilleure {
+			chercher(loin)
+		}
+	}
+	chercher(t.racine)
+
+	place = t.places[meilleur.place]
+	return place, distanceKm(p, place.Geo.GeoPoint), true
+}
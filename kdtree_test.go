@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Lieu le plus proche par parcours de tous les lieux géocodés
+func plusProcheExhaustif(places []Location, p GeoPoint) (Location, float64) {
+	var meilleur Location
+	meilleure := math.Inf(1)
+	for _, place := range places {
+		if place.Geo == nil {
+			continue
+		}
+		if km := distanceKm(p, place.Geo.GeoPoint); km < meilleure {
+			meilleur, meilleure = place, km
+		}
+	}
+	return meilleur, meilleure
+}
+
+func pointAleatoire(r *rand.Rand) GeoPoint {
+	// Latitude tirée uniformément sur la sphère, pour ne pas concentrer les points aux pôles
+	return GeoPoint{Lat: math.Asin(2*r.Float64()-1) * 180 / math.Pi, Lng: r.Float64()*360 - 180}
+}
+
+func TestKDTreeNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var places []Location
+	for i := 0; i < 500; i++ {
+		place := Location{Key: fmt.Sprintf("lieu_%d-test", i)}
+		// Quelques lieux sans coordonnées, que l'arbre doit ignorer
+		if i%10 != 0 {
+			place.Geo = &GeoPlace{GeoPoint: pointAleatoire(r)}
+		}
+		places = append(places, place)
+	}
+	// Lieux proches de l'antiméridien et des pôles
+	for _, p := range []GeoPoint{{Lat: 0, Lng: 179.9}, {Lat: 0, Lng: -179.9}, {Lat: 89.9, Lng: 10}, {Lat: -89.9, Lng: -170}} {
+		places = append(places, Location{Key: fmt.Sprintf("%v", p), Geo: &GeoPlace{GeoPoint: p}})
+	}
+	tree := construireKDTree(places)
+
+	cibles := []GeoPoint{{Lat: 0, Lng: 180}, {Lat: 0, Lng: -180}, {Lat: 90, Lng: 0}, {Lat: -90, Lng: 0}, {Lat: 0, Lng: 0}}
+	for i := 0; i < 2000; i++ {
+		cibles = append(cibles, pointAleatoire(r))
+	}
+	for _, p := range cibles {
+		place, km, ok := tree.Nearest(p)
+		attendu, kmAttendu := plusProcheExhaustif(places, p)
+		if !ok {
+			t.Fatalf("%v : aucun lieu trouvé", p)
+		}
+		if math.Abs(km-kmAttendu) > 1e-6 {
+			t.Errorf("%v : %s à %.6f km, attendu %s à %.6f km", p, place.Key, km, attendu.Key, kmAttendu)
+		}
+	}
+}
+
+func TestKDTreeVide(t *testing.T) {
+	var nilTree *KDTree
+	for _, tree := range []*KDTree{nilTree, construireKDTree(nil), construireKDTree([]Location{{Key: "atlantis-ocean"}})} {
+		if _, _, ok := tree.Nearest(GeoPoint{}); ok {
+			t.Errorf("arbre sans lieu géocodé : lieu trouvé")
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const mapTemplatePath = "map.html"
+
+const (
+	maxZoom          = 18
+	tailleTuile      = 256 // largeur du monde en pixels au zoom 0
+	tailleCelluleMap = 64  // côté en pixels des cellules de regroupement
+	latMaxMercator   = 85.05112878
+)
+
+// Structure passée au template map.html
+type MapPage struct {
+	Dataset   *Dataset
+	Search    string // paramètres de recherche transmis à /api/v1/map
+	RetourURL string // page de résultats de la même recherche
+}
+
+// Page de la carte des concerts : /map, avec les mêmes paramètres de recherche que /search
+func mapHandler(w http.ResponseWriter, r *http.Request) {
+	d := datasetPourRequete(w)
+	if d == nil {
+		return
+	}
+	page := MapPage{Dataset: d, Search: r.URL.RawQuery, RetourURL: "/"}
+	if page.Search != "" {
+		page.RetourURL = "/search?" + page.Search
+	}
+	executerTemplate(w, mapTemplatePath, http.StatusOK, page)
+}
+
+// Structure MapJSON : marqueurs de la carte pour une emprise et un niveau de zoom
+type MapJSON struct {
+	Zoom       int          `json:"zoom"`
+	BBox       [4]float64   `json:"bbox"` // ouest, sud, est, nord
+	Clusters   []MapCluster `json:"clusters"`
+	Countries  []MapLabel   `json:"countries"`
+	Unresolved []string     `json:"unresolved"` // lieux des résultats absents du gazetteer
+}
+
+// Structure MapCluster : lieux proches regroupés en un marqueur
+type MapCluster struct {
+	Lat           float64     `json:"lat"`
+	Lng           float64     `json:"lng"`
+	Label         string      `json:"label"`
+	LocationCount int         `json:"locationCount"`
+	ArtistCount   int         `json:"artistCount"`
+	ConcertCount  int         `json:"concertCount"`
+	BBox          [4]float64  `json:"bbox"` // emprise des lieux du groupe, pour zoomer dessus
+	Artists       []MapArtist `json:"artists"`
+}
+
+// Structure MapArtist : concerts d'un artiste dans un groupe de la carte
+type MapArtist struct {
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Concerts []MapConcert `json:"concerts"`
+}
+
+type MapConcert struct {
+	Date     string `json:"date"`
+	City     string `json:"city"`
+	Country  string `json:"country"`
+	Upcoming bool   `json:"upcoming"`
+}
+
+// Structure MapLabel : nom de pays placé au centre de ses lieux de concert
+type MapLabel struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+}
+
+// Position d'un point en pixels dans la projection Web Mercator au zoom donné
+func projeterMercator(p GeoPoint, zoom int) (x, y float64) {
+	taille := math.Ldexp(tailleTuile, zoom)
+	lat := math.Max(-latMaxMercator, math.Min(latMaxMercator, p.Lat)) * math.Pi / 180
+	x = (p.Lng + 180) / 360 * taille
+	y = (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * taille
+	return x, y
+}
+
+// Emprise ouest,sud,est,nord. L'ouest peut être supérieur à l'est quand l'emprise traverse l'antiméridien.
+func parseBBox(s string) ([4]float64, error) {
+	if s == "" {
+		return [4]float64{-180, -90, 180, 90}, nil
+	}
+	parts := strings.Split(s, ",")
+	var b [4]float64
+	if len(parts) != 4 {
+		return b, &ParamError{Param: "bbox", Value: s, Message: "ouest,sud,est,nord attendus"}
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return b, &ParamError{Param: "bbox", Value: s, Message: "ouest,sud,est,nord attendus"}
+		}
+		b[i] = v
+	}
+	if !coordonneesValides(b[1], b[0]) || !coordonneesValides(b[3], b[2]) {
+		return b, &ParamError{Param: "bbox", Value: s, Message: "latitudes entre -90 et 90 et longitudes entre -180 et 180 attendues"}
+	}
+	if b[1] > b[3] {
+		return b, &ParamError{Param: "bbox", Value: s, Message: "sud supérieur au nord"}
+	}
+	return b, nil
+}
+
+func dansBBox(b [4]float64, p GeoPoint) bool {
+	if p.Lat < b[1] || p.Lat > b[3] {
+		return false
+	}
+	if b[0] <= b[2] {
+		return p.Lng >= b[0] && p.Lng <= b[2]
+	}
+	return p.Lng >= b[0] || p.Lng <= b[2]
+}
+
+func paramZoom(q url.Values) (int, error) {
+	s := strings.TrimSpace(q.Get("zoom"))
+	if s == "" {
+		return 0, nil
+	}
+	z, err := strconv.Atoi(s)
+	if err != nil || z < 0 || z > maxZoom {
+		return 0, &ParamError{Param: "zoom", Value: s, Message: fmt.Sprintf("nombre entier entre 0 et %d attendu", maxZoom)}
+	}
+	return z, nil
+}
+
+// /api/v1/map?bbox=ouest,sud,est,nord&zoom=Z : concerts des artistes trouvés dans l'emprise,
+// regroupés par cellules de 64 pixels de la projection Web Mercator au zoom demandé
+func apiMap(w http.ResponseWriter, r *http.Request, d *Dataset) {
+	q := r.URL.Query()
+	bbox, err := parseBBox(strings.TrimSpace(q.Get("bbox")))
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	zoom, err := paramZoom(q)
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	Results, ok := resultatsDepuisRequete(w, r, d)
+	if !ok {
+		return
+	}
+	ecrireJSON(w, http.StatusOK, regrouperConcerts(d, Results, bbox, zoom))
+}
+
+// Regroupe les concerts géocodés de l'emprise par cellule et prépare les noms de pays
+func regrouperConcerts(d *Dataset, Results []Result, bbox [4]float64, zoom int) MapJSON {
+	type cellule struct{ x, y int }
+	type groupe struct {
+		cluster  MapCluster
+		lieux    map[string]GeoPoint
+		artistes map[int]int // position dans cluster.Artists
+		sommeLat float64
+		sommeLng float64
+	}
+	groupes := make(map[cellule]*groupe)
+	inconnus := make(map[string]bool)
+
+	for _, res := range Results {
+		for _, c := range res.RelevantConcerts() {
+			if c.Geo == nil {
+				inconnus[c.Location] = true
+				continue
+			}
+			if !dansBBox(bbox, c.Geo.GeoPoint) {
+				continue
+			}
+			x, y := projeterMercator(c.Geo.GeoPoint, zoom)
+			cle := cellule{int(x) / tailleCelluleMap, int(y) / tailleCelluleMap}
+			g, ok := groupes[cle]
+			if !ok {
+				g = &groupe{lieux: make(map[string]GeoPoint), artistes: make(map[int]int)}
+				g.cluster.BBox = [4]float64{c.Geo.Lng, c.Geo.Lat, c.Geo.Lng, c.Geo.Lat}
+				groupes[cle] = g
+			}
+			g.lieux[c.Location] = c.Geo.GeoPoint
+			g.sommeLat += c.Geo.Lat
+			g.sommeLng += c.Geo.Lng
+			g.cluster.ConcertCount++
+			b := &g.cluster.BBox
+			b[0], b[1] = math.Min(b[0], c.Geo.Lng), math.Min(b[1], c.Geo.Lat)
+			b[2], b[3] = math.Max(b[2], c.Geo.Lng), math.Max(b[3], c.Geo.Lat)
+
+			i, ok := g.artistes[res.ID]
+			if !ok {
+				i = len(g.cluster.Artists)
+				g.artistes[res.ID] = i
+				g.cluster.Artists = append(g.cluster.Artists, MapArtist{ID: res.ID, Name: res.Name})
+			}
+			g.cluster.Artists[i].Concerts = append(g.cluster.Artists[i].Concerts, MapConcert{
+				Date:     c.Date.Format("2006-01-02"),
+				City:     c.City,
+				Country:  c.Country,
+				Upcoming: c.Upcoming,
+			})
+		}
+	}
+
+	carte := MapJSON{Zoom: zoom, BBox: bbox, Clusters: []MapCluster{}, Unresolved: []string{}}
+	for _, g := range groupes {
+		cl := g.cluster
+		// Le marqueur est placé au barycentre des concerts du groupe
+		cl.Lat = g.sommeLat / float64(cl.ConcertCount)
+		cl.Lng = g.sommeLng / float64(cl.ConcertCount)
+		cl.LocationCount = len(g.lieux)
+		cl.ArtistCount = len(cl.Artists)
+		if cl.LocationCount == 1 {
+			cl.Label = cl.Artists[0].Concerts[0].City
+		} else {
+			cl.Label = fmt.Sprintf("%d lieux", cl.LocationCount)
+		}
+		sort.Slice(cl.Artists, func(i, j int) bool {
+			if r := compareNoms(cl.Artists[i].Name, cl.Artists[j].Name); r != 0 {
+				return r < 0
+			}
+			return cl.Artists[i].ID < cl.Artists[j].ID
+		})
+		carte.Clusters = append(carte.Clusters, cl)
+	}
+	sort.Slice(carte.Clusters, func(i, j int) bool {
+		a, b := carte.Clusters[i], carte.Clusters[j]
+		if a.ConcertCount != b.ConcertCount {
+			return a.ConcertCount > b.ConcertCount
+		}
+		if a.Lat != b.Lat {
+			return a.Lat > b.Lat
+		}
+		return a.Lng < b.Lng
+	})
+	for cle := range inconnus {
+		carte.Unresolved = append(carte.Unresolved, cle)
+	}
+	sort.Strings(carte.Unresolved)
+	carte.Countries = etiquettesPays(d, bbox)
+	return carte
+}
+
+// Noms des pays de concert, placés au centre de leurs lieux, pour se repérer sans fond de carte
+func etiquettesPays(d *Dataset, bbox [4]float64) []MapLabel {
+	type somme struct {
+		lat, lng float64
+		n        int
+	}
+	pays := make(map[string]*somme)
+	for _, p := range d.Places {
+		if p.Geo == nil {
+			continue
+		}
+		s, ok := pays[p.Geo.Country.Name]
+		if !ok {
+			s = &somme{}
+			pays[p.Geo.Country.Name] = s
+		}
+		s.lat += p.Geo.Lat
+		s.lng += p.Geo.Lng
+		s.n++
+	}
+	labels := []MapLabel{}
+	for nom, s := range pays {
+		centre := GeoPoint{Lat: s.lat / float64(s.n), Lng: s.lng / float64(s.n)}
+		if dansBBox(bbox, centre) {
+			labels = append(labels, MapLabel{Name: nom, Lat: centre.Lat, Lng: centre.Lng})
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
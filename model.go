@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Format des dates de l'API (JJ-MM-AAAA)
+const apiDateLayout = "02-01-2006"
+
+// Structure Artist : artiste avec ses dates typées et ses concerts joints depuis les relations
+type Artist struct {
+	ID           int
+	Image        string
+	Name         string
+	Members      []string
+	CreationDate int
+	FirstAlbum   time.Time
+	Concerts     []Concert // triés par date croissante
+	Tour         TourStats // bilan de la tournée, calculé après le géocodage
+}
+
+// Structure Concert : une date de concert dans un lieu
+type Concert struct {
+	Date     time.Time
+	City     string
+	Country  string
+	Upcoming bool      // date marquée d'un "*" dans l'api Dates
+	Location string    // clé "ville-pays" d'origine
+	Geo      *GeoPlace // coordonnées du lieu, nil s'il est absent du gazetteer
+}
+
+// Structure Location : un lieu de concert décodé depuis une clé "ville-pays"
+type Location struct {
+	Key     string
+	City    string
+	Country string
+	Geo     *GeoPlace // nil si le lieu est absent du gazetteer
+}
+
+// Structure Anomaly : donnée de l'API qui n'a pas pu être décodée ou jointe
+type Anomaly struct {
+	ArtistID int
+	Feed     string
+	Value    string
+	Message  string
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("artiste %d, %s : %q %s", a.ArtistID, a.Feed, a.Value, a.Message)
+}
+
+// Date du premier album au format de l'API
+func (a *Artist) FirstAlbumText() string {
+	if a.FirstAlbum.IsZero() {
+		return ""
+	}
+	return a.FirstAlbum.Format(apiDateLayout)
+}
+
+// Concert le plus récent de l'artiste, nil s'il n'en a aucun
+func (a *Artist) LastConcert() *Concert {
+	if len(a.Concerts) == 0 {
+		return nil
+	}
+	return &a.Concerts[len(a.Concerts)-1]
+}
+
+// Prochain concert à venir de l'artiste, nil s'il n'en a aucun
+func (a *Artist) NextConcert() *Concert {
+	for i := range a.Concerts {
+		if a.Concerts[i].Upcoming {
+			return &a.Concerts[i]
+		}
+	}
+	return nil
+}
+
+// Nombre de pays différents où l'artiste a joué, comptés comme dans le bilan de tournée
+func (a *Artist) CountryCount() int {
+	return len(a.Tour.Countries)
+}
+
+// Structure ConcertGroup : concerts d'un artiste dans un même lieu
+type ConcertGroup struct {
+	City     string
+	Country  string
+	Location string
+	Concerts []Concert
+}
+
+// Regroupe les concerts par ville et pays. Les groupes sont dans l'ordre de leur premier concert,
+// et les concerts de chaque groupe par date croissante.
+func (a *Artist) ConcertsByLocation() []ConcertGroup {
+	var groups []ConcertGroup
+	index := make(map[string]int)
+	for _, c := range a.Concerts {
+		i, ok := index[c.Location]
+		if !ok {
+			i = len(groups)
+			index[c.Location] = i
+			groups = append(groups, ConcertGroup{City: c.City, Country: c.Country, Location: c.Location})
+		}
+		groups[i].Concerts = append(groups[i].Concerts, c)
+	}
+	return groups
+}
+
+// Date d'un concert au format de l'API
+func (c Concert) DateText() string {
+	return c.Date.Format(apiDateLayout)
+}
+
+// Décode une date de l'API en retirant le marqueur "*" des concerts à venir
+func parseAPIDate(s string) (date time.Time, upcoming bool, err error) {
+	upcoming = strings.HasPrefix(s, "*")
+	date, err = time.Parse(apiDateLayout, strings.TrimPrefix(s, "*"))
+	return date, upcoming, err
+}
+
+// Décode une clé "ville-pays" (ex : "los_angeles-usa") en nom de ville et de pays lisibles
+func parseLocation(key string) (Location, error) {
+	ville, pays, ok := strings.Cut(key, "-")
+	if !ok || ville == "" || pays == "" || strings.Contains(pays, "-") {
+		return Location{Key: key}, fmt.Errorf("clé de lieu attendue au format ville-pays")
+	}
+	return Location{
+		Key:     key,
+		City:    nomLisible(ville),
+		Country: nomPays(pays),
+	}, nil
+}
+
+// "new_york" devient "New York"
+func nomLisible(s string) string {
+	mots := strings.Split(s, "_")
+	for i, mot := range mots {
+		if mot != "" {
+			r, taille := utf8.DecodeRuneInString(mot)
+			mots[i] = string(unicode.ToUpper(r)) + mot[taille:]
+		}
+	}
+	return strings.Join(mots, " ")
+}
+
+// Les sigles de pays ("usa", "uk") sont mis en majuscules
+func nomPays(s string) string {
+	if len(s) <= 3 {
+		return strings.ToUpper(s)
+	}
+	return nomLisible(s)
+}
+
+// Construit le modèle typé à partir des quatre flux et relève les anomalies de décodage
+func construireModele(artists []ArtistsInfo, locations LocationsInfo, dates DatesInfo, relations RelationsInfo) ([]*Artist, []Location, []Anomaly) {
+	var anomalies []Anomaly
+	signaler := func(id int, feed, value, message string) {
+		anomalies = append(anomalies, Anomaly{ArtistID: id, Feed: feed, Value: value, Message: message})
+	}
+
+	// Dates de chaque artiste marquées comme à venir dans l'api Dates
+	aVenir := make(map[int]map[time.Time]bool, len(dates.Index))
+	for _, index := range dates.Index {
+		aVenir[index.ID] = make(map[time.Time]bool)
+		for _, s := range index.Dates {
+			date, upcoming, err := parseAPIDate(s)
+			if err != nil {
+				signaler(index.ID, "dates", s, "n'est pas une date JJ-MM-AAAA")
+				continue
+			}
+			if upcoming {
+				aVenir[index.ID][date] = true
+			}
+		}
+	}
+
+	// Lieux connus, décodés une seule fois
+	lieux := make(map[string]Location)
+	lieu := func(id int, feed, key string) (Location, bool) {
+		if loc, ok := lieux[key]; ok {
+			return loc, true
+		}
+		loc, err := parseLocation(key)
+		if err != nil {
+			signaler(id, feed, key, err.Error())
+			return loc, false
+		}
+		lieux[key] = loc
+		return loc, true
+	}
+	for _, index := range locations.Index {
+		for _, key := range index.Locations {
+			lieu(index.ID, "locations", key)
+		}
+	}
+
+	relationsByID := make(map[int]map[string][]string, len(relations.Index))
+	for _, index := range relations.Index {
+		relationsByID[index.ID] = index.DatesLocations
+	}
+
+	model := make([]*Artist, 0, len(artists))
+	for _, info := range artists {
+		a := &Artist{
+			ID:           info.ID,
+			Image:        info.Image,
+			Name:         info.Name,
+			Members:      info.Members,
+			CreationDate: info.CreationDate,
+		}
+		if firstAlbum, _, err := parseAPIDate(info.FirstAlbum); err != nil {
+			signaler(info.ID, "artists", info.FirstAlbum, "premier album : n'est pas une date JJ-MM-AAAA")
+		} else {
+			a.FirstAlbum = firstAlbum
+		}
+
+		datesLocations, ok := relationsByID[info.ID]
+		if !ok {
+			signaler(info.ID, "relation", "", "aucune relation pour cet artiste")
+		}
+		for key, datesLieu := range datesLocations {
+			loc, ok := lieu(info.ID, "relation", key)
+			if !ok {
+				continue
+			}
+			for _, s := range datesLieu {
+				date, upcoming, err := parseAPIDate(s)
+				if err != nil {
+					signaler(info.ID, "relation", s, "n'est pas une date JJ-MM-AAAA")
+					continue
+				}
+				a.Concerts = append(a.Concerts, Concert{
+					Date:     date,
+					City:     loc.City,
+					Country:  loc.Country,
+					Upcoming: upcoming || aVenir[info.ID][date],
+					Location: key,
+				})
+			}
+		}
+		sort.SliceStable(a.Concerts, func(i, j int) bool {
+			if !a.Concerts[i].Date.Equal(a.Concerts[j].Date) {
+				return a.Concerts[i].Date.Before(a.Concerts[j].Date)
+			}
+			return a.Concerts[i].Location < a.Concerts[j].Location
+		})
+		model = append(model, a)
+	}
+	// Ordre par défaut des résultats, indépendant de l'ordre de l'API
+	sort.SliceStable(model, func(i, j int) bool {
+		return model[i].ID < model[j].ID
+	})
+
+	places := make([]Location, 0, len(lieux))
+	for _, loc := range lieux {
+		places = append(places, loc)
+	}
+	sort.Slice(places, func(i, j int) bool {
+		return places[i].Key < places[j].Key
+	})
+
+	return model, places, anomalies
+}
+
+// Affiche les anomalies relevées lors de la construction du modèle
+func signalerAnomalies(anomalies []Anomaly) {
+	if len(anomalies) == 0 {
+		return
+	}
+	log.Printf("%d anomalie(s) dans les données de l'API :\n", len(anomalies))
+	for _, a := range anomalies {
+		log.Println(" -", a)
+	}
+}
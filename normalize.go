@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Lettres que la décomposition NFD ne sépare pas d'un diacritique (lettres barrées, ligatures...),
+// ramenées à leurs lettres de base. Seules les minuscules sont listées, la rune étant mise en
+// minuscule avant.
+var lettresSansDecomposition = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'þ': "th",
+	'ð': "d",
+	'đ': "d",
+	'ħ': "h",
+	'ı': "i",
+	'ł': "l",
+	'ŀ': "l",
+	'ŉ': "n",
+	'ø': "o",
+	'ŧ': "t",
+}
+
+// Ramène une rune à sa forme de comparaison : minuscule sans accent. Renvoie "" pour les
+// diacritiques isolés et les apostrophes, qui sont ignorés ("Guns N' Roses" comme "Guns N Roses").
+// Les autres caractères qui ne sont ni des lettres ni des chiffres servent de séparateurs.
+func replierRune(r rune) (forme string, separateur bool) {
+	r = unicode.ToLower(r)
+	switch {
+	case unicode.Is(unicode.Mn, r), r == '\'', r == '’', r == '`':
+		return "", false
+	case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+		return string(r), false
+	case lettresSansDecomposition[r] != "":
+		return lettresSansDecomposition[r], false
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		// Décomposition NFD puis suppression des diacritiques (catégorie Mn) : "é" donne "e", "ẃ" donne "w"
+		var b strings.Builder
+		for _, d := range norm.NFD.String(string(r)) {
+			if !unicode.Is(unicode.Mn, d) {
+				b.WriteRune(d)
+			}
+		}
+		return b.String(), false
+	default:
+		return "", true
+	}
+}
+
+// Forme normalisée d'un texte utilisée par toutes les recherches : minuscules, sans accents,
+// ponctuation, tirets et "_" remplacés par des espaces. "São_Paulo", "sao-paulo" et "SAO PAULO"
+// donnent tous "sao paulo".
+func normaliser(s string) string {
+	var b strings.Builder
+	espace := false
+	for _, r := range s {
+		forme, separateur := replierRune(r)
+		if separateur {
+			espace = b.Len() > 0
+			continue
+		}
+		if forme == "" {
+			continue
+		}
+		if espace {
+			b.WriteByte(' ')
+			espace = false
+		}
+		b.WriteString(forme)
+	}
+	return b.String()
+}
+
+// Forme normalisée sans espaces, pour que "acdc" corresponde à "AC/DC"
+func normaliserCompact(s string) string {
+	return strings.ReplaceAll(normaliser(s), " ", "")
+}
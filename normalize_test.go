@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestNormaliser(t *testing.T) {
+	tests := []struct{ texte, attendu string }{
+		{"São_Paulo", "sao paulo"},
+		{"sao-paulo", "sao paulo"},
+		{"SAO PAULO", "sao paulo"},
+		{"Beyoncé", "beyonce"},
+		{"Motörhead", "motorhead"},
+		{"Guns N' Roses", "guns n roses"},
+		{"AC/DC", "ac dc"},
+		// Lettres absentes de toute table, repliées par la décomposition NFD
+		{"Ẃẁ ǵ ḱ ṕ", "ww g k p"},
+		// Diacritique combinant déjà séparé de sa lettre
+		{"Café", "cafe"},
+		// Lettres sans décomposition canonique
+		{"Straße Ærø Œuvre Łódź Þór", "strasse aero oeuvre lodz thor"},
+		{"", ""},
+		{" -- ", ""},
+	}
+	for _, tt := range tests {
+		if n := normaliser(tt.texte); n != tt.attendu {
+			t.Errorf("normaliser(%q) = %q, attendu %q", tt.texte, n, tt.attendu)
+		}
+	}
+}
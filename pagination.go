@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+)
+
+// Nombre de résultats par page par défaut et maximum
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// Paramètres qui ne font pas partie de la recherche elle-même
+var paramsPagination = []string{"page", "per_page", "cursor"}
+
+// Structure Pagination : position d'une page dans une liste de résultats
+type Pagination struct {
+	Total   int
+	Page    int
+	Pages   int
+	PerPage int
+	Start   int // index du premier élément de la page
+	End     int // index suivant le dernier élément de la page
+
+	PrevURL    string
+	NextURL    string
+	PrevCursor string
+	NextCursor string
+}
+
+// Contenu d'un curseur : position du premier élément de la page, identifiant de cet élément
+// pour retrouver sa place si les données ont changé, et empreinte de la recherche
+type cursor struct {
+	Start  int    `json:"s"`
+	Anchor string `json:"a"`
+	Query  string `json:"q"`
+}
+
+// Empreinte des paramètres de recherche, hors pagination
+func empreinteRecherche(q url.Values) string {
+	copie := url.Values{}
+	for k, v := range q {
+		copie[k] = v
+	}
+	for _, p := range paramsPagination {
+		copie.Del(p)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(copie.Encode()))
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func encoderCurseur(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decoderCurseur(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.Start < 0 {
+		return c, &ParamError{Param: "cursor", Value: s, Message: "curseur invalide"}
+	}
+	return c, nil
+}
+
+// Calcule la page demandée par "page"/"per_page" ou par "cursor" dans une liste de n éléments.
+// cle(i) renvoie l'identifiant stable de l'élément i, utilisé par les curseurs.
+func paginer(q url.Values, n int, cle func(i int) string) (Pagination, error) {
+	p := Pagination{Total: n, Page: 1, PerPage: defaultPerPage}
+
+	if s := q.Get("per_page"); s != "" {
+		perPage, err := strconv.Atoi(s)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			return p, &ParamError{Param: "per_page", Value: s, Message: fmt.Sprintf("nombre entre 1 et %d attendu", maxPerPage)}
+		}
+		p.PerPage = perPage
+	}
+
+	empreinte := empreinteRecherche(q)
+	if s := q.Get("cursor"); s != "" {
+		c, err := decoderCurseur(s)
+		if err != nil {
+			return p, err
+		}
+		if c.Query != empreinte {
+			return p, &ParamError{Param: "cursor", Value: s, Message: "curseur obtenu avec une autre recherche"}
+		}
+		p.Start = min(c.Start, n)
+		// Si les données ont été rafraîchies, l'élément d'ancrage a pu changer de place
+		if p.Start >= n || cle(p.Start) != c.Anchor {
+			for i := 0; i < n; i++ {
+				if cle(i) == c.Anchor {
+					p.Start = i
+					break
+				}
+			}
+		}
+		p.Page = p.Start/p.PerPage + 1
+	} else if s := q.Get("page"); s != "" {
+		page, err := strconv.Atoi(s)
+		if err != nil || page < 1 {
+			return p, &ParamError{Param: "page", Value: s, Message: "nombre entier positif attendu"}
+		}
+		p.Page = page
+		// Borné avant la multiplication, qui déborderait pour une page très grande
+		if page-1 > n/p.PerPage {
+			p.Start = n
+		} else {
+			p.Start = min((page-1)*p.PerPage, n)
+		}
+	}
+
+	p.End = min(p.Start+p.PerPage, n)
+	p.Pages = (n + p.PerPage - 1) / p.PerPage
+
+	if p.Start > 0 {
+		prev, prevPage := max(p.Start-p.PerPage, 0), max(p.Page-1, 1)
+		// Au-delà de la fin, la page précédente est la dernière page non vide
+		if p.Page > p.Pages {
+			prev, prevPage = (p.Pages-1)*p.PerPage, p.Pages
+		}
+		p.PrevCursor = encoderCurseur(cursor{Start: prev, Anchor: cle(prev), Query: empreinte})
+		p.PrevURL = urlPage(q, "page", strconv.Itoa(prevPage))
+	}
+	if p.End < n {
+		p.NextCursor = encoderCurseur(cursor{Start: p.End, Anchor: cle(p.End), Query: empreinte})
+		p.NextURL = urlPage(q, "page", strconv.Itoa(p.Page+1))
+	}
+	return p, nil
+}
+
+// Paramètres de la requête avec la pagination remplacée par name=value
+func urlPage(q url.Values, name, value string) string {
+	copie := url.Values{}
+	for k, v := range q {
+		copie[k] = v
+	}
+	for _, p := range paramsPagination {
+		copie.Del(p)
+	}
+	if q.Get("per_page") != "" {
+		copie.Set("per_page", q.Get("per_page"))
+	}
+	copie.Set(name, value)
+	return "?" + copie.Encode()
+}
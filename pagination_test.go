@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// Clés "0", "1", ... pour une liste de n éléments
+func clesNumerotees(n int) []string {
+	cles := make([]string, n)
+	for i := range cles {
+		cles[i] = strconv.Itoa(i)
+	}
+	return cles
+}
+
+func TestPaginerBornes(t *testing.T) {
+	cles := clesNumerotees(45)
+	cle := func(i int) string { return cles[i] }
+
+	tests := []struct {
+		requete    string
+		start, end int
+		page       int
+		pages      int
+		prev, next bool
+		prevURL    string
+	}{
+		{"", 0, 20, 1, 3, false, true, ""},
+		{"page=2", 20, 40, 2, 3, true, true, "?page=1"},
+		{"page=3", 40, 45, 3, 3, true, false, "?page=2"},
+		// Au-delà de la fin, le lien précédent mène à la dernière page
+		{"page=4", 45, 45, 4, 3, true, false, "?page=3"},
+		{"page=461168601842738792", 45, 45, 461168601842738792, 3, true, false, "?page=3"},
+		{"page=9223372036854775807&per_page=100", 45, 45, 9223372036854775807, 1, true, false, "?page=1&per_page=100"},
+		{"per_page=10&page=5", 40, 45, 5, 5, true, false, "?page=4&per_page=10"},
+		{"per_page=10&page=7&search=queen", 45, 45, 7, 5, true, false, "?page=5&per_page=10&search=queen"},
+		{"per_page=100", 0, 45, 1, 1, false, false, ""},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.requete)
+		p, err := paginer(q, len(cles), cle)
+		if err != nil {
+			t.Errorf("%q : erreur inattendue %v", tt.requete, err)
+			continue
+		}
+		if p.Start != tt.start || p.End != tt.end || p.Page != tt.page || p.Pages != tt.pages {
+			t.Errorf("%q : start=%d end=%d page=%d pages=%d, attendu %d %d %d %d",
+				tt.requete, p.Start, p.End, p.Page, p.Pages, tt.start, tt.end, tt.page, tt.pages)
+		}
+		if (p.PrevCursor != "") != tt.prev || (p.NextCursor != "") != tt.next {
+			t.Errorf("%q : précédent=%v suivant=%v, attendu %v %v", tt.requete, p.PrevCursor != "", p.NextCursor != "", tt.prev, tt.next)
+		}
+		if p.PrevURL != tt.prevURL {
+			t.Errorf("%q : lien précédent %q, attendu %q", tt.requete, p.PrevURL, tt.prevURL)
+		}
+	}
+}
+
+func TestPaginerListeVide(t *testing.T) {
+	p, err := paginer(url.Values{"page": {"3"}}, 0, func(int) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Start != 0 || p.End != 0 || p.Pages != 0 || p.NextCursor != "" {
+		t.Errorf("liste vide : %+v", p)
+	}
+}
+
+func TestPaginerErreurs(t *testing.T) {
+	cle := func(i int) string { return strconv.Itoa(i) }
+	autre, _ := paginer(url.Values{"search": {"queen"}}, 45, cle)
+
+	tests := []struct {
+		requete url.Values
+		param   string
+	}{
+		{url.Values{"page": {"0"}}, "page"},
+		{url.Values{"page": {"-1"}}, "page"},
+		{url.Values{"page": {"deux"}}, "page"},
+		{url.Values{"per_page": {"0"}}, "per_page"},
+		{url.Values{"per_page": {"101"}}, "per_page"},
+		{url.Values{"cursor": {"pas-un-curseur"}}, "cursor"},
+		{url.Values{"cursor": {autre.NextCursor}, "search": {"soja"}}, "cursor"},
+	}
+	for _, tt := range tests {
+		_, err := paginer(tt.requete, 45, cle)
+		var perr *ParamError
+		if !errors.As(err, &perr) || perr.Param != tt.param {
+			t.Errorf("%v : erreur %v, attendu une erreur sur %s", tt.requete, err, tt.param)
+		}
+	}
+}
+
+func TestPaginerCurseur(t *testing.T) {
+	cles := clesNumerotees(45)
+	q := url.Values{"search": {"queen"}, "per_page": {"10"}}
+	p, err := paginer(q, len(cles), func(i int) string { return cles[i] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sans changement des données, le curseur suivant donne la page 2
+	q.Set("cursor", p.NextCursor)
+	p2, err := paginer(q, len(cles), func(i int) string { return cles[i] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2.Start != 10 || p2.End != 20 || p2.Page != 2 {
+		t.Errorf("page suivante : start=%d end=%d page=%d", p2.Start, p2.End, p2.Page)
+	}
+
+	// Après un rafraîchissement qui ajoute deux éléments en tête, la page reprend à l'élément d'ancrage
+	rafraichies := append([]string{"nouveau-1", "nouveau-2"}, cles...)
+	p3, err := paginer(q, len(rafraichies), func(i int) string { return rafraichies[i] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p3.Start != 12 || rafraichies[p3.Start] != "10" {
+		t.Errorf("après rafraîchissement : start=%d (%s), attendu 12 (10)", p3.Start, rafraichies[p3.Start])
+	}
+
+	// Si l'élément d'ancrage a disparu, la position enregistrée est conservée
+	reduites := append(append([]string{}, cles[:10]...), cles[11:]...)
+	p4, err := paginer(q, len(reduites), func(i int) string { return reduites[i] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p4.Start != 10 {
+		t.Errorf("ancrage disparu : start=%d, attendu 10", p4.Start)
+	}
+
+	// Un curseur au-delà de la fin d'une liste raccourcie donne une page vide
+	p5, err := paginer(q, 5, func(i int) string { return cles[i] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p5.Start != 5 || p5.End != 5 {
+		t.Errorf("liste raccourcie : start=%d end=%d", p5.Start, p5.End)
+	}
+
+	// Depuis une page au-delà de la fin, le curseur précédent mène à la dernière page
+	q.Del("cursor")
+	q.Set("page", "9")
+	p6, err := paginer(q, len(cles), func(i int) string { return cles[i] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Del("page")
+	q.Set("cursor", p6.PrevCursor)
+	p7, err := paginer(q, len(cles), func(i int) string { return cles[i] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p7.Start != 40 || p7.End != 45 || p7.Page != 5 {
+		t.Errorf("retour depuis la page 9 : start=%d end=%d page=%d", p7.Start, p7.End, p7.Page)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Langage de recherche du champ "search" :
+//
+//	queen                      nom de l'artiste ou d'un membre (approché)
+//	"pink floyd"               expression entre guillemets
+//	name:queen                 nom de l'artiste uniquement
+//	member:"Freddie Mercury"   nom d'un membre uniquement
+//	city:paris country:uk      ville ou pays d'un concert
+//	location:"los angeles"     ville ou pays d'un concert
+//	year:1970..1980            année de création (valeur exacte ou plage a..b, a.. ou ..b)
+//	members:4..                nombre de membres
+//	album:1973                 date du premier album (AAAA ou AAAA-MM-JJ, ou plage)
+//	date:2019                  date de concert (AAAA ou AAAA-MM-JJ, ou plage)
+//	after:2019-01-01           concert à partir de cette date
+//	before:2020-12-31          concert jusqu'à cette date
+//	is:upcoming is:past        concert à venir ou passé
+//	-country:usa               négation de n'importe quel critère
+//	country:uk OR country:fr   l'un ou l'autre critère (OR en majuscules, entre deux critères)
+//
+// Les critères sur les concerts (city, country, location, date, after, before, is) doivent être
+// vérifiés par un même concert, sauf s'ils sont niés. Une alternative OR entre critères de concert
+// compte comme un seul critère de ce concert ; dès qu'elle contient un autre critère, elle porte sur
+// l'artiste. Les mots libres reliés par OR sont cherchés dans les noms sans classement par pertinence.
+
+// Résultat de l'analyse du champ de recherche
+type rechercheAnalysee struct {
+	texte    string      // mots libres, cherchés dans les noms
+	filtres  And         // critères sur l'artiste (dont les négations)
+	concerts SameConcert // critères sur un même concert
+}
+
+// Élément du champ de recherche : [-][champ:]valeur
+type jeton struct {
+	pos        int // position (en caractères) dans la saisie, pour les messages d'erreur
+	negatif    bool
+	champ      string
+	valeur     string
+	guillemets bool
+}
+
+// Erreur d'analyse du champ de recherche, affichée dans la page
+func erreurRecherche(saisie string, pos int, format string, args ...any) error {
+	return &ParamError{
+		Param:   "search",
+		Value:   saisie,
+		Message: fmt.Sprintf("caractère %d : %s", pos+1, fmt.Sprintf(format, args...)),
+	}
+}
+
+// Découpe la saisie en jetons, en respectant les guillemets
+func decouperJetons(saisie string) ([]jeton, error) {
+	runes := []rune(saisie)
+	var jetons []jeton
+	i := 0
+	for i < len(runes) {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		j := jeton{pos: i}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			j.negatif = true
+			i++
+		}
+
+		// Nom de champ éventuel, suivi de ":"
+		debut := i
+		for i < len(runes) && (unicode.IsLetter(runes[i]) || runes[i] == '_') {
+			i++
+		}
+		if i < len(runes) && runes[i] == ':' && i > debut {
+			j.champ = strings.ToLower(string(runes[debut:i]))
+			i++
+		} else {
+			i = debut
+		}
+
+		// Valeur : entre guillemets ou jusqu'au prochain espace
+		if i < len(runes) && runes[i] == '"' {
+			fin := i + 1
+			for fin < len(runes) && runes[fin] != '"' {
+				fin++
+			}
+			if fin == len(runes) {
+				return nil, erreurRecherche(saisie, i, "guillemet fermant manquant")
+			}
+			j.valeur = string(runes[i+1 : fin])
+			j.guillemets = true
+			i = fin + 1
+		} else {
+			debut = i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			j.valeur = string(runes[debut:i])
+		}
+
+		if j.champ != "" && strings.TrimSpace(j.valeur) == "" {
+			return nil, erreurRecherche(saisie, j.pos, "valeur manquante après %s:", j.champ)
+		}
+		// Un tiret isolé (" - ") n'est pas un critère
+		if j.champ == "" && !j.guillemets && (j.valeur == "" || j.valeur == "-") {
+			continue
+		}
+		jetons = append(jetons, j)
+	}
+	return jetons, nil
+}
+
+// Analyse le champ de recherche et le traduit en filtres
+func analyserRecherche(saisie string) (rechercheAnalysee, error) {
+	var res rechercheAnalysee
+	jetons, err := decouperJetons(saisie)
+	if err != nil {
+		return res, err
+	}
+
+	// Les termes reliés par OR forment un groupe, les autres un groupe d'un seul terme
+	type terme struct {
+		jeton  jeton
+		filtre Filter
+	}
+	var groupes [][]terme
+	for i, j := range jetons {
+		if estOu(j) {
+			if i == 0 || i == len(jetons)-1 || estOu(jetons[i+1]) {
+				return res, erreurRecherche(saisie, j.pos, "OR doit relier deux critères")
+			}
+			continue
+		}
+		f, err := filtreDuJeton(saisie, j)
+		if err != nil {
+			return res, err
+		}
+		if i > 0 && estOu(jetons[i-1]) {
+			groupes[len(groupes)-1] = append(groupes[len(groupes)-1], terme{j, f})
+		} else {
+			groupes = append(groupes, []terme{{j, f}})
+		}
+	}
+
+	var mots []string
+	for _, groupe := range groupes {
+		if len(groupe) > 1 {
+			// Alternative : entre critères de concert, elle reste un critère du même concert
+			var alternatives Or
+			var concerts AnyConcert
+			for _, t := range groupe {
+				f := t.filtre
+				if f == nil {
+					f = NameFilter{Query: t.jeton.valeur}
+				}
+				if t.jeton.negatif {
+					f = Not{Filter: f}
+				}
+				alternatives = append(alternatives, f)
+				if c, ok := f.(ConcertFilter); ok {
+					concerts = append(concerts, c)
+				}
+			}
+			if len(concerts) == len(alternatives) {
+				res.concerts = append(res.concerts, concerts)
+			} else {
+				res.filtres = append(res.filtres, alternatives)
+			}
+			continue
+		}
+		j, f := groupe[0].jeton, groupe[0].filtre
+		switch {
+		case f == nil:
+			// Mot libre : ajouté au texte cherché dans les noms
+			mots = append(mots, j.valeur)
+		case j.negatif:
+			res.filtres = append(res.filtres, Not{Filter: f})
+		default:
+			if c, ok := f.(ConcertFilter); ok {
+				res.concerts = append(res.concerts, c)
+			} else {
+				res.filtres = append(res.filtres, f)
+			}
+		}
+	}
+	res.texte = strings.Join(mots, " ")
+	return res, nil
+}
+
+// Mot-clé OR entre deux critères (en majuscules, sans guillemets)
+func estOu(j jeton) bool {
+	return j.valeur == "OR" && j.champ == "" && !j.negatif && !j.guillemets
+}
+
+// Filtre correspondant à un jeton, nil pour un mot libre (non nié)
+func filtreDuJeton(saisie string, j jeton) (Filter, error) {
+	v := j.valeur
+	switch j.champ {
+	case "":
+		if j.negatif {
+			return NameFilter{Query: v}, nil
+		}
+		return nil, nil
+	case "name":
+		return ArtistNameFilter{Query: v}, nil
+	case "member":
+		return MemberFilter{Query: v}, nil
+	case "city":
+		return CityFilter{Query: v}, nil
+	case "country":
+		return CountryFilter{Query: v}, nil
+	case "location":
+		return LocationFilter{Query: v}, nil
+	case "year":
+		min, max, err := analyserPlageEntiers(v)
+		if err != nil {
+			return nil, erreurRecherche(saisie, j.pos, "year:%s : %v", v, err)
+		}
+		return CreationYearFilter{Min: min, Max: max}, nil
+	case "members":
+		min, max, err := analyserPlageEntiers(v)
+		if err != nil {
+			return nil, erreurRecherche(saisie, j.pos, "members:%s : %v", v, err)
+		}
+		return MemberCountFilter{Min: min, Max: max}, nil
+	case "album":
+		from, to, err := analyserPlageDates(v)
+		if err != nil {
+			return nil, erreurRecherche(saisie, j.pos, "album:%s : %v", v, err)
+		}
+		return FirstAlbumFilter{From: from, To: to}, nil
+	case "date":
+		from, to, err := analyserPlageDates(v)
+		if err != nil {
+			return nil, erreurRecherche(saisie, j.pos, "date:%s : %v", v, err)
+		}
+		return ConcertPeriodFilter{From: from, To: to}, nil
+	case "after":
+		from, _, err := analyserDate(v)
+		if err != nil {
+			return nil, erreurRecherche(saisie, j.pos, "after:%s : %v", v, err)
+		}
+		return ConcertPeriodFilter{From: from}, nil
+	case "before":
+		_, to, err := analyserDate(v)
+		if err != nil {
+			return nil, erreurRecherche(saisie, j.pos, "before:%s : %v", v, err)
+		}
+		return ConcertPeriodFilter{To: to}, nil
+	case "is":
+		switch strings.ToLower(v) {
+		case "upcoming":
+			return UpcomingFilter{Upcoming: true}, nil
+		case "past":
+			return UpcomingFilter{Upcoming: false}, nil
+		}
+		return nil, erreurRecherche(saisie, j.pos, "is:%s : upcoming ou past attendu", v)
+	}
+	return nil, erreurRecherche(saisie, j.pos, "champ inconnu %q", j.champ)
+}
+
+// "1970", "1970..1980", "1970.." ou "..1980"
+func analyserPlageEntiers(v string) (int, int, error) {
+	debut, fin, plage := strings.Cut(v, "..")
+	if !plage {
+		fin = debut
+	}
+	var bornes [2]int
+	for i, s := range []string{debut, fin} {
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("nombre entier positif attendu")
+		}
+		bornes[i] = n
+	}
+	if plage && bornes[0] == 0 && bornes[1] == 0 {
+		return 0, 0, fmt.Errorf("au moins une borne attendue")
+	}
+	if bornes[0] != 0 && bornes[1] != 0 && bornes[0] > bornes[1] {
+		return 0, 0, fmt.Errorf("plage inversée")
+	}
+	return bornes[0], bornes[1], nil
+}
+
+// Date AAAA-MM-JJ, AAAA-MM ou AAAA : renvoie le premier et le dernier jour de la période
+func analyserDate(v string) (time.Time, time.Time, error) {
+	if d, err := time.Parse("2006-01-02", v); err == nil {
+		return d, d, nil
+	}
+	if d, err := time.Parse("2006-01", v); err == nil {
+		return d, d.AddDate(0, 1, -1), nil
+	}
+	if d, err := time.Parse("2006", v); err == nil {
+		return d, d.AddDate(1, 0, -1), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("date AAAA, AAAA-MM ou AAAA-MM-JJ attendue")
+}
+
+// Date ou plage de dates "debut..fin" (une des bornes peut être omise)
+func analyserPlageDates(v string) (time.Time, time.Time, error) {
+	debut, fin, plage := strings.Cut(v, "..")
+	if !plage {
+		return analyserDate(v)
+	}
+	var from, to time.Time
+	var err error
+	if debut != "" {
+		if from, _, err = analyserDate(debut); err != nil {
+			return from, to, err
+		}
+	}
+	if fin != "" {
+		if _, to, err = analyserDate(fin); err != nil {
+			return from, to, err
+		}
+	}
+	if from.IsZero() && to.IsZero() {
+		return from, to, fmt.Errorf("au moins une borne attendue")
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return from, to, fmt.Errorf("plage inversée")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecouperJetons(t *testing.T) {
+	tests := []struct {
+		saisie string
+		jetons []jeton
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"queen", []jeton{{pos: 0, valeur: "queen"}}},
+		{"pink  floyd", []jeton{{pos: 0, valeur: "pink"}, {pos: 6, valeur: "floyd"}}},
+		{`"pink floyd"`, []jeton{{pos: 0, valeur: "pink floyd", guillemets: true}}},
+		{`member:"Freddie Mercury"`, []jeton{{pos: 0, champ: "member", valeur: "Freddie Mercury", guillemets: true}}},
+		{"City:Paris", []jeton{{pos: 0, champ: "city", valeur: "Paris"}}},
+		{"-country:usa", []jeton{{pos: 0, negatif: true, champ: "country", valeur: "usa"}}},
+		{"-queen", []jeton{{pos: 0, negatif: true, valeur: "queen"}}},
+		{`-"pink floyd"`, []jeton{{pos: 0, negatif: true, valeur: "pink floyd", guillemets: true}}},
+		{"year:1970..1980", []jeton{{pos: 0, champ: "year", valeur: "1970..1980"}}},
+		{"members:4..", []jeton{{pos: 0, champ: "members", valeur: "4.."}}},
+		// Le tiret isolé est ignoré, le tiret à l'intérieur d'un mot est conservé
+		{"ac - dc", []jeton{{pos: 0, valeur: "ac"}, {pos: 5, valeur: "dc"}}},
+		{"jay-z", []jeton{{pos: 0, valeur: "jay-z"}}},
+		// Sans nom de champ avant ":", la valeur est un mot libre
+		{":queen", []jeton{{pos: 0, valeur: ":queen"}}},
+		{"AC/DC", []jeton{{pos: 0, valeur: "AC/DC"}}},
+		// Positions comptées en caractères, pas en octets
+		{"Motörhead city:oslo", []jeton{{pos: 0, valeur: "Motörhead"}, {pos: 10, champ: "city", valeur: "oslo"}}},
+		{`queen OR "the who"`, []jeton{{pos: 0, valeur: "queen"}, {pos: 6, valeur: "OR"}, {pos: 9, valeur: "the who", guillemets: true}}},
+		{`""`, []jeton{{pos: 0, valeur: "", guillemets: true}}},
+	}
+	for _, tt := range tests {
+		jetons, err := decouperJetons(tt.saisie)
+		if err != nil {
+			t.Errorf("%q : erreur inattendue %v", tt.saisie, err)
+			continue
+		}
+		if !reflect.DeepEqual(jetons, tt.jetons) {
+			t.Errorf("%q : %+v, attendu %+v", tt.saisie, jetons, tt.jetons)
+		}
+	}
+}
+
+func TestAnalyserRecherche(t *testing.T) {
+	jour := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		saisie   string
+		texte    string
+		filtres  And
+		concerts SameConcert
+	}{
+		{"", "", nil, nil},
+		{"queen", "queen", nil, nil},
+		{`freddie "pink floyd"`, "freddie pink floyd", nil, nil},
+		{"name:queen member:brian", "", And{ArtistNameFilter{Query: "queen"}, MemberFilter{Query: "brian"}}, nil},
+		{"queen city:london", "queen", nil, SameConcert{CityFilter{Query: "london"}}},
+		{"city:paris date:2019", "", nil, SameConcert{
+			CityFilter{Query: "paris"},
+			ConcertPeriodFilter{From: jour("2019-01-01"), To: jour("2019-12-31")},
+		}},
+		{"after:2019-03 before:2020-02-10", "", nil, SameConcert{
+			ConcertPeriodFilter{From: jour("2019-03-01")},
+			ConcertPeriodFilter{To: jour("2020-02-10")},
+		}},
+		{"is:Upcoming", "", nil, SameConcert{UpcomingFilter{Upcoming: true}}},
+
+		// Plages
+		{"year:1970..1980", "", And{CreationYearFilter{Min: 1970, Max: 1980}}, nil},
+		{"year:1970", "", And{CreationYearFilter{Min: 1970, Max: 1970}}, nil},
+		{"members:4..", "", And{MemberCountFilter{Min: 4}}, nil},
+		{"members:..2", "", And{MemberCountFilter{Max: 2}}, nil},
+		{"album:1973..1975-06", "", And{FirstAlbumFilter{From: jour("1973-01-01"), To: jour("1975-06-30")}}, nil},
+		{"date:..2000-02", "", nil, SameConcert{ConcertPeriodFilter{To: jour("2000-02-29")}}},
+
+		// Négations : toujours des critères sur l'artiste
+		{"-queen", "", And{Not{Filter: NameFilter{Query: "queen"}}}, nil},
+		{"-country:usa city:paris", "", And{Not{Filter: CountryFilter{Query: "usa"}}}, SameConcert{CityFilter{Query: "paris"}}},
+
+		// OR
+		{"country:uk OR country:fr", "", nil, SameConcert{
+			AnyConcert{CountryFilter{Query: "uk"}, CountryFilter{Query: "fr"}},
+		}},
+		{"city:paris OR city:lyon date:2019", "", nil, SameConcert{
+			AnyConcert{CityFilter{Query: "paris"}, CityFilter{Query: "lyon"}},
+			ConcertPeriodFilter{From: jour("2019-01-01"), To: jour("2019-12-31")},
+		}},
+		{"queen OR members:5..", "", And{Or{NameFilter{Query: "queen"}, MemberCountFilter{Min: 5}}}, nil},
+		{"city:paris OR -members:..3", "", And{Or{CityFilter{Query: "paris"}, Not{Filter: MemberCountFilter{Max: 3}}}}, nil},
+		{"a OR b OR c", "", And{Or{NameFilter{Query: "a"}, NameFilter{Query: "b"}, NameFilter{Query: "c"}}}, nil},
+		// "or" en minuscules ou entre guillemets est un mot libre
+		{"guns or roses", "guns or roses", nil, nil},
+		{`queen "OR" who`, "queen OR who", nil, nil},
+	}
+	for _, tt := range tests {
+		res, err := analyserRecherche(tt.saisie)
+		if err != nil {
+			t.Errorf("%q : erreur inattendue %v", tt.saisie, err)
+			continue
+		}
+		if res.texte != tt.texte {
+			t.Errorf("%q : texte %q, attendu %q", tt.saisie, res.texte, tt.texte)
+		}
+		if !reflect.DeepEqual(res.filtres, tt.filtres) {
+			t.Errorf("%q : filtres %#v, attendu %#v", tt.saisie, res.filtres, tt.filtres)
+		}
+		if !reflect.DeepEqual(res.concerts, tt.concerts) {
+			t.Errorf("%q : concerts %#v, attendu %#v", tt.saisie, res.concerts, tt.concerts)
+		}
+	}
+}
+
+func TestAnalyserRechercheErreurs(t *testing.T) {
+	tests := []struct {
+		saisie  string
+		message string
+	}{
+		{`"pink floyd`, "caractère 1 : guillemet fermant manquant"},
+		{`queen member:"brian`, "caractère 14 : guillemet fermant manquant"},
+		{"queen city:", "caractère 7 : valeur manquante après city:"},
+		{`city:"  "`, "caractère 1 : valeur manquante après city:"},
+		{"queen genre:rock", `caractère 7 : champ inconnu "genre"`},
+		{"year:abc", "caractère 1 : year:abc : nombre entier positif attendu"},
+		{"year:1980..1970", "caractère 1 : year:1980..1970 : plage inversée"},
+		{"members:..", "caractère 1 : members:.. : au moins une borne attendue"},
+		{"members:0", "caractère 1 : members:0 : nombre entier positif attendu"},
+		{"album:1973-13", "caractère 1 : album:1973-13 : date AAAA, AAAA-MM ou AAAA-MM-JJ attendue"},
+		{"date:2020..2019", "caractère 1 : date:2020..2019 : plage inversée"},
+		{"date:..", "caractère 1 : date:.. : au moins une borne attendue"},
+		{"x after:hier", "caractère 3 : after:hier : date AAAA, AAAA-MM ou AAAA-MM-JJ attendue"},
+		{"-is:soon", "caractère 1 : is:soon : upcoming ou past attendu"},
+		{"Motörhead year:x", "caractère 11 : year:x"},
+		{"OR queen", "caractère 1 : OR doit relier deux critères"},
+		{"queen OR", "caractère 7 : OR doit relier deux critères"},
+		{"queen OR OR who", "caractère 7 : OR doit relier deux critères"},
+	}
+	for _, tt := range tests {
+		_, err := analyserRecherche(tt.saisie)
+		var perr *ParamError
+		if !errors.As(err, &perr) || perr.Param != "search" || perr.Value != tt.saisie {
+			t.Errorf("%q : erreur %v, attendu une erreur sur search", tt.saisie, err)
+			continue
+		}
+		if !strings.HasPrefix(perr.Message, tt.message) {
+			t.Errorf("%q : %q, attendu %q", tt.saisie, perr.Message, tt.message)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Délai entre deux tentatives tant qu'aucune donnée n'a pu être chargée
+const retryIntervalSansDonnees = 30 * time.Second
+
+// Structure refreshStatus : résultat de la dernière tentative de rafraîchissement
+type refreshStatus struct {
+	mu          sync.Mutex
+	lastAttempt time.Time
+	lastErr     error
+}
+
+var statutRafraichissement refreshStatus
+
+func (s *refreshStatus) set(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAttempt = time.Now()
+	s.lastErr = err
+}
+
+func (s *refreshStatus) get() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastAttempt, s.lastErr
+}
+
+// Recharge les données toutes les `interval` en arrière-plan.
+// Si l'API ne répond pas, l'instantané précédent continue d'être servi.
+func lancerRafraichissement(src Source, interval time.Duration) {
+	go func() {
+		for {
+			attente := interval
+			if currentDataset.Load() == nil && attente > retryIntervalSansDonnees {
+				attente = retryIntervalSansDonnees
+			}
+			time.Sleep(attente)
+			err := rafraichirDataset(src)
+			statutRafraichissement.set(err)
+			if err != nil && currentDataset.Load() != nil {
+				log.Printf("Données conservées de l'instantané du %s\n", currentDataset.Load().LoadedAt.Format(time.RFC3339))
+			}
+		}
+	}()
+}
+
+// Age de l'instantané
+func (d *Dataset) Age() time.Duration {
+	return time.Since(d.LoadedAt)
+}
+
+// Indique si le dernier rafraîchissement a échoué, donc si les données servies sont périmées
+func (d *Dataset) Stale() bool {
+	_, err := statutRafraichissement.get()
+	return err != nil
+}
+
+// Age de l'instantané en toutes lettres pour l'affichage dans la page
+func (d *Dataset) AgeText() string {
+	age := d.Age().Round(time.Second)
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("il y a %d s", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("il y a %d min", int(age.Minutes()))
+	default:
+		return fmt.Sprintf("il y a %d h %02d min", int(age.Hours()), int(age.Minutes())%60)
+	}
+}
+
+// Récupère l'instantané courant et écrit son âge dans les en-têtes de la réponse.
+// Renvoie nil (après avoir répondu 503) si aucune donnée n'a encore pu être chargée.
+func datasetPourRequete(w http.ResponseWriter) *Dataset {
+	d := currentDataset.Load()
+	if d == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryIntervalSansDonnees.Seconds())))
+		http.Error(w, "Erreur de récupération des infos API", http.StatusServiceUnavailable)
+		return nil
+	}
+	ecrireEntetesFraicheur(w, d)
+	return d
+}
+
+// Indique dans les en-têtes de la réponse la date et l'âge des données servies
+func ecrireEntetesFraicheur(w http.ResponseWriter, d *Dataset) {
+	w.Header().Set("X-Data-Loaded-At", d.LoadedAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("X-Data-Age", strconv.Itoa(int(d.Age().Seconds())))
+	if d.Stale() {
+		w.Header().Set("X-Data-Stale", "true")
+	}
+}
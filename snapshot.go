@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Nom du manifeste écrit à côté des fichiers exportés
+const fichierManifest = "manifest.json"
+
+// Structure du manifeste d'un export : date de récupération et empreinte de chaque fichier
+type SnapshotManifest struct {
+	Source    string                  `json:"source"`
+	FetchedAt time.Time               `json:"fetchedAt"`
+	Files     map[string]SnapshotFile `json:"files"`
+}
+
+// Description d'un fichier exporté
+type SnapshotFile struct {
+	URL    string `json:"url"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Commande "snapshot" : télécharge l'API et l'écrit dans un dossier lisible par -data
+func snapshotCommand(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	out := fs.String("out", "", "dossier de destination de l'export")
+	fs.Parse(args)
+	if *out == "" {
+		fs.Usage()
+		return fmt.Errorf("le flag -out est obligatoire")
+	}
+
+	apiInfo, err := recupJSON()
+	if err != nil {
+		return err
+	}
+
+	// Chaque flux est téléchargé puis décodé dans sa structure pour vérifier qu'il est exploitable
+	flux := []struct {
+		fichier string
+		url     string
+		cible   any
+	}{
+		{fichierArtists, apiInfo.ArtistsInfo, &[]ArtistsInfo{}},
+		{fichierLocations, apiInfo.LocationsInfo, &LocationsInfo{}},
+		{fichierDates, apiInfo.DatesInfo, &DatesInfo{}},
+		{fichierRelation, apiInfo.RelationsInfo, &RelationsInfo{}},
+	}
+
+	manifest := SnapshotManifest{
+		Source:    apiURL,
+		FetchedAt: time.Now().UTC(),
+		Files:     make(map[string]SnapshotFile, len(flux)),
+	}
+	contenus := make(map[string][]byte, len(flux))
+	for _, f := range flux {
+		body, err := telecharger(f.url)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, f.cible); err != nil {
+			return fmt.Errorf("%s : contenu invalide : %w", f.url, err)
+		}
+		sum := sha256.Sum256(body)
+		manifest.Files[f.fichier] = SnapshotFile{
+			URL:    f.url,
+			Size:   len(body),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+		contenus[f.fichier] = body
+	}
+
+	// Rien n'est écrit tant que les quatre flux ne sont pas valides
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for fichier, body := range contenus {
+		if err := os.WriteFile(filepath.Join(*out, fichier), body, 0o644); err != nil {
+			return err
+		}
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(*out, fichierManifest), append(manifestJSON, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	log.Printf("Export écrit dans %s\n", *out)
+	return nil
+}
+
+func telecharger(url string) ([]byte, error) {
+	resp, err := clientAPI.Get(url)
+	if err != nil {
+		log.Printf("Erreur lors de la requête GET : %v\n", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s : statut HTTP %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
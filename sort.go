@@ -0,0 +1,126 @@
+package main
+
+import (
+	"cmp"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Clé de tri des résultats
+type cleTri struct {
+	compare  func(a, b *Artist) int
+	manquant func(a *Artist) bool // valeur absente : l'artiste est placé en fin de liste dans les deux sens
+}
+
+// Critère de tri demandé : une clé et son sens
+type critereTri struct {
+	nom         string
+	cle         cleTri
+	decroissant bool
+}
+
+func compareDates(a, b time.Time) int {
+	return a.Compare(b)
+}
+
+// Compare deux noms comme la recherche les lit (sans accents, casse ni ponctuation),
+// puis tels quels pour départager "Beyoncé" et "Beyonce"
+func compareNoms(a, b string) int {
+	if r := strings.Compare(normaliser(a), normaliser(b)); r != 0 {
+		return r
+	}
+	return strings.Compare(a, b)
+}
+
+// Clés acceptées par le paramètre "sort"
+var clesTri = map[string]cleTri{
+	"name": {compare: func(a, b *Artist) int {
+		return compareNoms(a.Name, b.Name)
+	}},
+	"creation_date": {compare: func(a, b *Artist) int {
+		return cmp.Compare(a.CreationDate, b.CreationDate)
+	}},
+	"first_album": {
+		compare:  func(a, b *Artist) int { return compareDates(a.FirstAlbum, b.FirstAlbum) },
+		manquant: func(a *Artist) bool { return a.FirstAlbum.IsZero() },
+	},
+	"members": {compare: func(a, b *Artist) int {
+		return cmp.Compare(len(a.Members), len(b.Members))
+	}},
+	"concerts": {compare: func(a, b *Artist) int {
+		return cmp.Compare(len(a.Concerts), len(b.Concerts))
+	}},
+	"countries": {compare: func(a, b *Artist) int {
+		return cmp.Compare(a.CountryCount(), b.CountryCount())
+	}},
+	"next_concert": {
+		compare:  func(a, b *Artist) int { return compareDates(a.NextConcert().Date, b.NextConcert().Date) },
+		manquant: func(a *Artist) bool { return a.NextConcert() == nil },
+	},
+	"last_concert": {
+		compare:  func(a, b *Artist) int { return compareDates(a.LastConcert().Date, b.LastConcert().Date) },
+		manquant: func(a *Artist) bool { return a.LastConcert() == nil },
+	},
+}
+
+// Lit le paramètre "sort" : liste de clés séparées par des virgules, "-" devant une clé pour l'ordre décroissant.
+// Les anciennes cases à cocher "alpha" et "concert" sont traduites en "name" et "-last_concert".
+func triDepuisRequete(q url.Values) ([]critereTri, error) {
+	s := q.Get("sort")
+	if s == "" {
+		if q.Get("alpha") == "on" {
+			s = "name"
+		}
+		if q.Get("concert") == "on" {
+			s = "-last_concert"
+		}
+	}
+
+	var criteres []critereTri
+	for _, champ := range strings.Split(s, ",") {
+		champ = strings.TrimSpace(champ)
+		if champ == "" {
+			continue
+		}
+		c := critereTri{nom: strings.TrimPrefix(champ, "-"), decroissant: strings.HasPrefix(champ, "-")}
+		cle, ok := clesTri[c.nom]
+		if !ok {
+			return nil, &ParamError{Param: "sort", Value: s, Message: "clé de tri inconnue " + c.nom}
+		}
+		c.cle = cle
+		criteres = append(criteres, c)
+	}
+	return criteres, nil
+}
+
+// Trie les résultats selon les critères, dans l'ordre. L'ID départage les égalités pour que
+// la pagination reste stable quel que soit le tri.
+func trierResultats(Results []Result, criteres []critereTri) []Result {
+	tries := make([]Result, len(Results))
+	copy(tries, Results)
+	sort.SliceStable(tries, func(i, j int) bool {
+		a, b := tries[i].Artist, tries[j].Artist
+		for _, c := range criteres {
+			if c.cle.manquant != nil {
+				am, bm := c.cle.manquant(a), c.cle.manquant(b)
+				if am || bm {
+					if am == bm {
+						continue
+					}
+					return bm
+				}
+			}
+			r := c.cle.compare(a, b)
+			if c.decroissant {
+				r = -r
+			}
+			if r != 0 {
+				return r < 0
+			}
+		}
+		return a.ID < b.ID
+	})
+	return tries
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestTriParNom(t *testing.T) {
+	noms := []string{"Épica", "eagles", "AC/DC", "ABBA", "Beyoncé", "Beyonce", "Øystein", "Oasis", "Ed Sheeran"}
+	var Results []Result
+	for i, nom := range noms {
+		Results = append(Results, Result{Artist: &Artist{ID: i + 1, Name: nom}})
+	}
+
+	tests := []struct {
+		sort    string
+		attendu []string
+	}{
+		// Les accents et la ponctuation sont ignorés comme dans la recherche, le nom brut départage
+		{"name", []string{"ABBA", "AC/DC", "Beyonce", "Beyoncé", "eagles", "Ed Sheeran", "Épica", "Oasis", "Øystein"}},
+		{"-name", []string{"Øystein", "Oasis", "Épica", "Ed Sheeran", "eagles", "Beyoncé", "Beyonce", "AC/DC", "ABBA"}},
+	}
+	for _, tt := range tests {
+		criteres, err := triDepuisRequete(url.Values{"sort": {tt.sort}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var obtenus []string
+		for _, res := range trierResultats(Results, criteres) {
+			obtenus = append(obtenus, res.Name)
+		}
+		if !reflect.DeepEqual(obtenus, tt.attendu) {
+			t.Errorf("sort=%s : %q, attendu %q", tt.sort, obtenus, tt.attendu)
+		}
+	}
+}
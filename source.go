@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Adresse de l'index de l'API groupie tracker
+const apiURL = "https://groupietrackers.herokuapp.com/api"
+
+// Noms des fichiers attendus dans un dossier de données
+const (
+	fichierArtists   = "artists.json"
+	fichierLocations = "locations.json"
+	fichierDates     = "dates.json"
+	fichierRelation  = "relation.json"
+)
+
+// Structure Feeds : contenu brut des quatre flux de l'API
+type Feeds struct {
+	Artists   []ArtistsInfo
+	Locations LocationsInfo
+	Dates     DatesInfo
+	Relations RelationsInfo
+}
+
+// Source des données : l'API en ligne ou une copie sur disque
+type Source interface {
+	Fetch() (*Feeds, error)
+	String() string
+}
+
+// Source qui interroge l'API HTTP à partir de son index avec son propre client, dont le
+// délai borne la durée de chaque requête
+type HTTPSource struct {
+	IndexURL string
+	Client   *http.Client
+}
+
+func (s HTTPSource) String() string {
+	return s.IndexURL
+}
+
+func (s HTTPSource) Fetch() (*Feeds, error) {
+	var apiInfo GroupieTracker
+	if err := recupererJSON(s.Client, s.IndexURL, &apiInfo); err != nil {
+		return nil, err
+	}
+
+	var f Feeds
+	if err := recupererJSON(s.Client, apiInfo.ArtistsInfo, &f.Artists); err != nil {
+		return nil, err
+	}
+	if err := recupererJSON(s.Client, apiInfo.LocationsInfo, &f.Locations); err != nil {
+		return nil, err
+	}
+	if err := recupererJSON(s.Client, apiInfo.DatesInfo, &f.Dates); err != nil {
+		return nil, err
+	}
+	if err := recupererJSON(s.Client, apiInfo.RelationsInfo, &f.Relations); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Source qui lit artists.json, locations.json, dates.json et relation.json dans un dossier
+type DirSource struct {
+	Dir string
+}
+
+func (s DirSource) String() string {
+	return s.Dir
+}
+
+func (s DirSource) Fetch() (*Feeds, error) {
+	var f Feeds
+	if err := lireFichierJSON(filepath.Join(s.Dir, fichierArtists), &f.Artists); err != nil {
+		return nil, err
+	}
+	if err := lireFichierJSON(filepath.Join(s.Dir, fichierLocations), &f.Locations); err != nil {
+		return nil, err
+	}
+	if err := lireFichierJSON(filepath.Join(s.Dir, fichierDates), &f.Dates); err != nil {
+		return nil, err
+	}
+	if err := lireFichierJSON(filepath.Join(s.Dir, fichierRelation), &f.Relations); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func lireFichierJSON(path string, v any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Erreur lors de l'ouverture du fichier : %v\n", err)
+		return err
+	}
+	defer file.Close()
+
+	err = json.NewDecoder(file).Decode(v)
+	if err != nil {
+		log.Printf("Erreur lors du décodage JSON de %s : %v\n", path, err)
+		return fmt.Errorf("%s : %w", path, err)
+	}
+	return nil
+}
+
+// Choisit la source en fonction du flag -data : un dossier s'il est renseigné, l'API sinon
+func choisirSource(dir string) Source {
+	if dir != "" {
+		return DirSource{Dir: dir}
+	}
+	return HTTPSource{IndexURL: apiURL, Client: &http.Client{Timeout: delaiRequeteAPI}}
+}
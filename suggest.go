@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Nombre de suggestions renvoyées par défaut et maximum
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// Types de suggestion, du plus au moins prioritaire à pertinence égale
+var ordreTypesSuggestion = map[string]int{"artist": 0, "member": 1, "city": 2, "country": 3, "year": 4}
+
+// Structure Suggestion : proposition renvoyée par /suggest
+type Suggestion struct {
+	Type     string  `json:"type"` // artist, member, city, country ou year
+	Label    string  `json:"label"`
+	ArtistID int     `json:"artistId,omitempty"` // artiste concerné (artist, member)
+	Score    float64 `json:"score"`
+	Search   string  `json:"search"` // texte à placer dans le champ de recherche (voir query.go)
+}
+
+// Lit le nombre maximum de suggestions demandé
+func paramLimite(q url.Values) (int, error) {
+	s := q.Get("limit")
+	if s == "" {
+		return defaultSuggestLimit, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > maxSuggestLimit {
+		return 0, &ParamError{Param: "limit", Value: s, Message: fmt.Sprintf("nombre entre 1 et %d attendu", maxSuggestLimit)}
+	}
+	return n, nil
+}
+
+// Pertinence d'un libellé pour la saisie : début du libellé, puis début d'un mot, puis
+// n'importe où dans un mot. Avec approche, les fautes de frappe sont tolérées en dernier recours.
+func scoreSuggestion(saisie, label string, approche bool) (float64, bool) {
+	q, t := normaliser(saisie), normaliser(label)
+	if q == "" {
+		return 0, false
+	}
+	switch {
+	case strings.HasPrefix(t, q):
+		return 1, true
+	case strings.HasPrefix(normaliserCompact(label), normaliserCompact(saisie)):
+		return 0.9, true // "acdc" pour "AC/DC"
+	case strings.Contains(" "+t, " "+q):
+		return 0.8, true
+	case strings.Contains(t, q):
+		return 0.6, true
+	}
+	if approche {
+		if m, ok := rechercheApprochee(saisie, label); ok {
+			return 0.5 * m.Score, true
+		}
+	}
+	return 0, false
+}
+
+// Critère du langage de recherche pour une valeur, entre guillemets si elle contient des espaces
+func critereRecherche(champ, valeur string) string {
+	valeur = strings.ReplaceAll(valeur, `"`, "")
+	if strings.ContainsAny(valeur, " \t") {
+		valeur = `"` + valeur + `"`
+	}
+	return champ + ":" + valeur
+}
+
+// Suggestions pour la saisie, classées par pertinence
+func suggestions(d *Dataset, saisie string) []Suggestion {
+	var res []Suggestion
+	ajouter := func(s Suggestion, approche bool) {
+		if score, ok := scoreSuggestion(saisie, s.Label, approche); ok {
+			s.Score = score
+			res = append(res, s)
+		}
+	}
+
+	// Artistes et membres trouvés par l'index, fautes de frappe comprises
+	for _, id := range d.Index.CandidatsTexte(saisie).tries() {
+		artist := d.ArtistModel(id)
+		if artist == nil {
+			continue
+		}
+		search := artist.Name
+		if strings.ContainsAny(search, `:"`) || strings.HasPrefix(search, "-") {
+			search = critereRecherche("name", search) // le nom serait lu comme un critère
+		}
+		ajouter(Suggestion{Type: "artist", Label: artist.Name, ArtistID: artist.ID, Search: search}, true)
+		for _, member := range artist.Members {
+			ajouter(Suggestion{Type: "member", Label: member, ArtistID: artist.ID, Search: critereRecherche("member", member)}, true)
+		}
+	}
+
+	// Villes et pays de concert trouvés par l'index, dans l'ordre de Dataset.Places pour que
+	// chaque pays garde la clé de son premier lieu
+	pays := make(map[string]bool)
+	for _, i := range d.Index.CandidatsPlaces(saisie).tries() {
+		place := d.Places[i]
+		ville, codePays, _ := strings.Cut(place.Key, "-")
+		ajouter(Suggestion{
+			Type:   "city",
+			Label:  place.City + ", " + place.Country,
+			Search: critereRecherche("city", strings.ReplaceAll(ville, "_", " ")) + " " + critereRecherche("country", strings.ReplaceAll(codePays, "_", " ")),
+		}, false)
+		if !pays[place.Country] {
+			pays[place.Country] = true
+			ajouter(Suggestion{Type: "country", Label: place.Country, Search: critereRecherche("country", strings.ReplaceAll(codePays, "_", " "))}, false)
+		}
+	}
+
+	// Années de sortie du premier album contenant la saisie
+	if compact := normaliserCompact(saisie); compact != "" {
+		for _, label := range d.Index.TermesApprox(champAlbum, compact, 0) {
+			ajouter(Suggestion{Type: "year", Label: label, Search: critereRecherche("album", label)}, false)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		if res[i].Type != res[j].Type {
+			return ordreTypesSuggestion[res[i].Type] < ordreTypesSuggestion[res[j].Type]
+		}
+		if len(res[i].Label) != len(res[j].Label) {
+			return len(res[i].Label) < len(res[j].Label)
+		}
+		if res[i].Label != res[j].Label {
+			return res[i].Label < res[j].Label
+		}
+		return res[i].ArtistID < res[j].ArtistID
+	})
+	return res
+}
+
+// /suggest?query=...&limit=... : suggestions typées pour le champ de recherche
+func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	d := datasetPourRequete(w)
+	if d == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := paramLimite(q)
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res := suggestions(d, strings.TrimSpace(q.Get("query")))
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	if res == nil {
+		res = []Suggestion{}
+	}
+	ecrireJSON(w, http.StatusOK, res)
+}
+
+// Structure PlaceSuggestion : pays proposé par /suggestgeo, avec ses villes
+type PlaceSuggestion struct {
+	Type         string            `json:"type"` // country ou city
+	Label        string            `json:"label"`
+	Place        string            `json:"place"` // valeur du paramètre place qui filtre exactement ce lieu
+	ArtistCount  int               `json:"artistCount"`
+	ConcertCount int               `json:"concertCount"`
+	Score        float64           `json:"score"`
+	Cities       []PlaceSuggestion `json:"cities,omitempty"`
+}
+
+// Pays et villes de concert, avec le nombre d'artistes et de concerts de chacun. Un pays est
+// proposé si son nom correspond à la saisie (avec toutes ses villes) ou si une de ses villes
+// correspond (avec ces villes seulement).
+func suggestionsLieux(d *Dataset, saisie string) []PlaceSuggestion {
+	// Pays dont le nom ou une ville correspond à la saisie, d'après l'index
+	paysTrouves := make(map[string]bool)
+	for i := range d.Index.CandidatsPlaces(saisie) {
+		paysTrouves[cleLieuNormalisee(d.Places[i].Key, true)] = true
+	}
+	if len(paysTrouves) == 0 {
+		return nil
+	}
+
+	// Comptage en un seul parcours des concerts, limité aux pays trouvés. Chaque clé de l'API
+	// n'est normalisée qu'une fois.
+	type compte struct {
+		artistes map[int]bool
+		concerts int
+	}
+	type cles struct{ ville, pays string }
+	normalisees := make(map[string]cles)
+	comptes := make(map[string]*compte)
+	compter := func(cle string, artistID int) {
+		c := comptes[cle]
+		if c == nil {
+			c = &compte{artistes: make(map[int]bool)}
+			comptes[cle] = c
+		}
+		c.artistes[artistID] = true
+		c.concerts++
+	}
+	for _, artist := range d.Model {
+		for _, concert := range artist.Concerts {
+			k, ok := normalisees[concert.Location]
+			if !ok {
+				k = cles{cleLieuNormalisee(concert.Location, false), cleLieuNormalisee(concert.Location, true)}
+				normalisees[concert.Location] = k
+			}
+			if paysTrouves[k.pays] {
+				compter(k.ville, artist.ID)
+				compter(k.pays, artist.ID)
+			}
+		}
+	}
+	nouvelle := func(typ, label, place, cle string) PlaceSuggestion {
+		s := PlaceSuggestion{Type: typ, Label: label, Place: place}
+		if c := comptes[cle]; c != nil {
+			s.ArtistCount, s.ConcertCount = len(c.artistes), c.concerts
+		}
+		return s
+	}
+
+	// Les lieux sont regroupés par clé normalisée : "São Paulo" et "Sao Paulo" ne font qu'un
+	var res []PlaceSuggestion
+	villes := make(map[string][]PlaceSuggestion) // villes de chaque pays
+	vus := make(map[string]bool)
+	for _, place := range d.Places {
+		_, codePays, _ := strings.Cut(place.Key, "-")
+		clePays := cleLieuNormalisee(place.Key, true)
+		if !paysTrouves[clePays] {
+			continue
+		}
+		if !vus[clePays] {
+			vus[clePays] = true
+			pays := nouvelle("country", place.Country, codePays, clePays)
+			pays.Score, _ = scoreSuggestion(saisie, place.Country, false)
+			res = append(res, pays)
+		}
+		cleVille := cleLieuNormalisee(place.Key, false)
+		if vus[cleVille] {
+			continue
+		}
+		vus[cleVille] = true
+		ville := nouvelle("city", place.City, place.Key, cleVille)
+		ville.Score, _ = scoreSuggestion(saisie, place.City, false)
+		villes[clePays] = append(villes[clePays], ville)
+	}
+
+	var trouves []PlaceSuggestion
+	for _, pays := range res {
+		for _, ville := range villes[cleLieuNormalisee(pays.Place, false)] {
+			if pays.Score > 0 || ville.Score > 0 {
+				pays.Cities = append(pays.Cities, ville)
+			}
+		}
+		if len(pays.Cities) == 0 {
+			continue
+		}
+		sort.Slice(pays.Cities, func(i, j int) bool {
+			a, b := pays.Cities[i], pays.Cities[j]
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			if a.ConcertCount != b.ConcertCount {
+				return a.ConcertCount > b.ConcertCount
+			}
+			return a.Label < b.Label
+		})
+		// Un pays vaut au moins sa meilleure ville
+		pays.Score = max(pays.Score, pays.Cities[0].Score)
+		trouves = append(trouves, pays)
+	}
+	sort.Slice(trouves, func(i, j int) bool {
+		a, b := trouves[i], trouves[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.ArtistCount != b.ArtistCount {
+			return a.ArtistCount > b.ArtistCount
+		}
+		return a.Label < b.Label
+	})
+	return trouves
+}
+
+// /suggestgeo?query=...&limit=... : pays et villes de concert correspondant à la saisie
+func suggest_geoHandler(w http.ResponseWriter, r *http.Request) {
+	d := datasetPourRequete(w)
+	if d == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := paramLimite(q)
+	if err != nil {
+		erreurJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res := suggestionsLieux(d, strings.TrimSpace(q.Get("query")))
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	if res == nil {
+		res = []PlaceSuggestion{}
+	}
+	ecrireJSON(w, http.StatusOK, res)
+}
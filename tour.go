@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Structure TourStats : bilan de la tournée d'un artiste, calculé une fois à la construction du Dataset
+// à partir de ses concerts triés par date et géocodés
+type TourStats struct {
+	ConcertCount       int
+	DistanceKm         float64   // somme des trajets entre deux concerts géocodés successifs
+	Legs               int       // trajets entre deux lieux différents
+	LongestLeg         *TourLeg  // nil sans trajet
+	BusiestMonth       time.Time // premier jour du mois le plus chargé, zéro sans concert
+	BusiestMonthCount  int
+	Countries          []string // noms de pays, canoniques quand le gazetteer connaît le pays
+	Continents         []string // codes de continent (AF, AS, EU, NA, OC, SA) des lieux géocodés
+	UnresolvedConcerts int      // concerts sans coordonnées, ignorés dans les distances
+}
+
+// Structure TourLeg : trajet entre deux concerts successifs
+type TourLeg struct {
+	From, To Concert
+	Km       float64
+}
+
+// Noms des continents du gazetteer
+var nomsContinents = map[string]string{
+	"AF": "Afrique",
+	"AN": "Antarctique",
+	"AS": "Asie",
+	"EU": "Europe",
+	"NA": "Amérique du Nord",
+	"OC": "Océanie",
+	"SA": "Amérique du Sud",
+}
+
+var nomsMois = [...]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"}
+
+// Calcule le bilan de tournée à partir de concerts triés par date croissante et géocodés par g
+func calculerTournee(g *Gazetteer, concerts []Concert) TourStats {
+	t := TourStats{ConcertCount: len(concerts)}
+	pays := make(map[string]string) // clé canonique du pays -> nom affiché
+	continents := make(map[string]bool)
+	parMois := make(map[time.Time]int)
+
+	var precedent *Concert
+	for i := range concerts {
+		c := &concerts[i]
+		mois := time.Date(c.Date.Year(), c.Date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		parMois[mois]++
+		if n := parMois[mois]; n > t.BusiestMonthCount || (n == t.BusiestMonthCount && mois.Before(t.BusiestMonth)) {
+			t.BusiestMonth, t.BusiestMonthCount = mois, n
+		}
+
+		if cle, nom := paysConcert(g, *c); pays[cle] == "" {
+			pays[cle] = nom // premier nom rencontré pour ce pays
+		}
+		if c.Geo == nil {
+			t.UnresolvedConcerts++
+			continue
+		}
+		if c.Geo.Country.Continent != "" {
+			continents[c.Geo.Country.Continent] = true
+		}
+
+		if precedent != nil && precedent.Geo.GeoPoint != c.Geo.GeoPoint {
+			km := distanceKm(precedent.Geo.GeoPoint, c.Geo.GeoPoint)
+			t.DistanceKm += km
+			t.Legs++
+			if t.LongestLeg == nil || km > t.LongestLeg.Km {
+				t.LongestLeg = &TourLeg{From: *precedent, To: *c, Km: km}
+			}
+		}
+		precedent = c
+	}
+
+	noms := make(map[string]bool, len(pays))
+	for _, nom := range pays {
+		noms[nom] = true
+	}
+	t.Countries = clesTriees(noms)
+	t.Continents = clesTriees(continents)
+	return t
+}
+
+// Pays d'un concert : celui du gazetteer quand il est connu, même si la ville ne l'est pas,
+// sinon la partie pays de la clé de l'API ("uk" et "UK" ne comptent qu'une fois)
+func paysConcert(g *Gazetteer, c Concert) (cle, nom string) {
+	if c.Geo != nil {
+		return c.Geo.Country.Name, c.Geo.Country.Name
+	}
+	if pays, ok := g.Country(c.Location); ok {
+		return pays.Name, pays.Name
+	}
+	return "?" + cleLieuNormalisee(c.Location, true), c.Country
+}
+
+func clesTriees(m map[string]bool) []string {
+	cles := make([]string, 0, len(m))
+	for cle := range m {
+		cles = append(cles, cle)
+	}
+	sort.Strings(cles)
+	return cles
+}
+
+// "août 2019", vide sans concert
+func (t TourStats) BusiestMonthText() string {
+	if t.BusiestMonth.IsZero() {
+		return ""
+	}
+	return nomsMois[t.BusiestMonth.Month()-1] + " " + t.BusiestMonth.Format("2006")
+}
+
+// Noms des continents visités
+func (t TourStats) ContinentNames() []string {
+	noms := make([]string, len(t.Continents))
+	for i, code := range t.Continents {
+		noms[i] = nomsContinents[code]
+		if noms[i] == "" {
+			noms[i] = code
+		}
+	}
+	return noms
+}
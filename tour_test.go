@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCalculerTourneePays(t *testing.T) {
+	concert := func(date, cle, pays string) Concert {
+		d, _ := time.Parse("2006-01-02", date)
+		return Concert{Date: d, Location: cle, Country: pays, Geo: gazetteer.Resolve(cle)}
+	}
+	concerts := []Concert{
+		concert("2019-01-10", "london-uk", "UK"),
+		// Ville absente du gazetteer dans un pays connu : même pays que Londres
+		concert("2019-01-12", "nulle_part-uk", "UK"),
+		concert("2019-02-01", "los_angeles-usa", "USA"),
+		// Pays inconnu : la partie pays de la clé, comptée une seule fois malgré les accents
+		concert("2019-03-01", "atlantis-ocean", "Ocean"),
+		concert("2019-03-02", "lemurie-océan", "Océan"),
+	}
+	if concerts[0].Geo == nil || concerts[1].Geo != nil || concerts[3].Geo != nil {
+		t.Fatal("lieux de test inattendus dans le gazetteer")
+	}
+
+	tour := calculerTournee(gazetteer, concerts)
+	if attendu := []string{"Ocean", "United Kingdom", "United States"}; !reflect.DeepEqual(tour.Countries, attendu) {
+		t.Errorf("pays %v, attendu %v", tour.Countries, attendu)
+	}
+	if tour.UnresolvedConcerts != 3 {
+		t.Errorf("%d concerts non géocodés, attendu 3", tour.UnresolvedConcerts)
+	}
+
+	// Le tri par nombre de pays s'appuie sur le même décompte
+	a := &Artist{Concerts: concerts, Tour: tour}
+	if a.CountryCount() != 3 {
+		t.Errorf("CountryCount = %d, attendu 3", a.CountryCount())
+	}
+}